@@ -2,28 +2,47 @@ package app
 
 import (
 	"encoding/json"
-	"github.com/bdaler/crud/pkg/customers"
+	"github.com/bdaler/crud/cmd/app/middleawre"
+	"github.com/bdaler/crud/pkg/types"
 	"golang.org/x/crypto/bcrypt"
+	"io"
 	"net/http"
+	"strings"
 )
 
 func (s *Server) handleCustomerRegistration(w http.ResponseWriter, r *http.Request) {
-	var item *customers.Customer
+	var item *types.Customer
 	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
 		errorWriter(w, http.StatusBadRequest, err)
 		return
 	}
 
-	hashed, err := bcrypt.GenerateFromPassword([]byte(item.Password), bcrypt.DefaultCost)
+	validation := &types.ValidationError{}
+	if strings.TrimSpace(item.Name) == "" {
+		validation.Add("name", "required")
+	}
+	if strings.TrimSpace(item.Phone) == "" {
+		validation.Add("phone", "required")
+	}
+	if item.Password == "" {
+		validation.Add("password", "required")
+	}
+	if err := validation.Err(); err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(item.Password), s.cfg.BcryptCost)
 	if err != nil {
 		errorWriter(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	item.ID = 0
 	item.Password = string(hashed)
 	customer, err := s.customerSvc.Save(r.Context(), item)
 	if err != nil {
-		errorWriter(w, http.StatusInternalServerError, err)
+		serviceErrorWriter(w, err)
 		return
 	}
 
@@ -43,7 +62,7 @@ func (s *Server) handleCustomerGetToken(w http.ResponseWriter, r *http.Request)
 
 	token, err := s.customerSvc.Token(r.Context(), item.Login, item.Password)
 	if err != nil {
-		errorWriter(w, http.StatusBadRequest, err)
+		serviceErrorWriter(w, err)
 		return
 	}
 
@@ -51,11 +70,323 @@ func (s *Server) handleCustomerGetToken(w http.ResponseWriter, r *http.Request)
 }
 
 func (s *Server) handleCustomerGetProducts(w http.ResponseWriter, r *http.Request) {
-	items, err := s.customerSvc.Products(r.Context())
+	filter, err := parseProductFilter(r)
 	if err != nil {
-		errorWriter(w, http.StatusBadRequest, err)
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	items, err := s.customerSvc.Products(r.Context(), filter)
+	if err != nil {
+		serviceErrorWriter(w, err)
 		return
 	}
 
 	responseJSON(w, items)
 }
+
+func (s *Server) handleCustomerSearchProducts(w http.ResponseWriter, r *http.Request) {
+	validation := &types.ValidationError{}
+	search := &types.ProductSearch{
+		Query: strings.TrimSpace(r.URL.Query().Get("q")),
+		Limit: parseInt(r, "limit", validation),
+	}
+	if search.Query == "" {
+		validation.Add("q", "required")
+	}
+	if err := validation.Err(); err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	items, err := s.customerSvc.SearchProducts(r.Context(), search)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, items)
+}
+
+func (s *Server) handleCustomerGetCategories(w http.ResponseWriter, r *http.Request) {
+	items, err := s.customerSvc.Categories(r.Context())
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, items)
+}
+
+func (s *Server) handleCustomerGetMe(w http.ResponseWriter, r *http.Request) {
+	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	customer, err := s.customerSvc.ByID(r.Context(), id)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, customer)
+}
+
+func (s *Server) handleCustomerChangeMe(w http.ResponseWriter, r *http.Request) {
+	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var item struct {
+		Name        string `json:"name"`
+		OldPassword string `json:"old_password"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	validation := &types.ValidationError{}
+	if strings.TrimSpace(item.Name) == "" {
+		validation.Add("name", "required")
+	}
+	if item.NewPassword != "" && item.OldPassword == "" {
+		validation.Add("old_password", "required")
+	}
+	if err := validation.Err(); err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	customer, err := s.customerSvc.ChangeProfile(r.Context(), id, item.Name, item.OldPassword, item.NewPassword)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, customer)
+}
+
+func (s *Server) handleCustomerGetPurchases(w http.ResponseWriter, r *http.Request) {
+	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	filter, err := parseSaleFilter(r)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	page, err := s.customerSvc.Purchases(r.Context(), id, filter)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, page)
+}
+
+func (s *Server) handleCustomerGetCart(w http.ResponseWriter, r *http.Request) {
+	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	cart, err := s.customerSvc.Cart(r.Context(), id)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, cart)
+}
+
+func (s *Server) handleCustomerSetCartItem(w http.ResponseWriter, r *http.Request) {
+	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	productID, err := pathID(r)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var item struct {
+		Qty int `json:"qty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cart, err := s.customerSvc.SetCartItem(r.Context(), id, productID, item.Qty)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, cart)
+}
+
+func (s *Server) handleCustomerRemoveCartItem(w http.ResponseWriter, r *http.Request) {
+	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	productID, err := pathID(r)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cart, err := s.customerSvc.RemoveCartItem(r.Context(), id, productID)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, cart)
+}
+
+func (s *Server) handleCustomerClearCart(w http.ResponseWriter, r *http.Request) {
+	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	if err = s.customerSvc.ClearCart(r.Context(), id); err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleCustomerCheckout(w http.ResponseWriter, r *http.Request) {
+	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	// body is optional
+	var item struct {
+		PromoCode string `json:"promo_code"`
+	}
+	if err = json.NewDecoder(r.Body).Decode(&item); err != nil && err != io.EOF {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sale, err := s.customerSvc.Checkout(r.Context(), id, item.PromoCode)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, sale)
+}
+
+func (s *Server) handleCustomerReserve(w http.ResponseWriter, r *http.Request) {
+	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var item struct {
+		Positions []*types.ReservationPosition `json:"positions"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	reservation, err := s.customerSvc.Reserve(r.Context(), id, item.Positions)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, reservation)
+}
+
+func (s *Server) handleCustomerGetReservation(w http.ResponseWriter, r *http.Request) {
+	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	reservationID, err := pathID(r)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	reservation, err := s.customerSvc.Reservation(r.Context(), id, reservationID)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, reservation)
+}
+
+func (s *Server) handleCustomerReleaseReservation(w http.ResponseWriter, r *http.Request) {
+	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	reservationID, err := pathID(r)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err = s.customerSvc.ReleaseReservation(r.Context(), id, reservationID); err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleCustomerConfirmReservation(w http.ResponseWriter, r *http.Request) {
+	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	reservationID, err := pathID(r)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sale, err := s.customerSvc.ConfirmReservation(r.Context(), id, reservationID)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, sale)
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"github.com/bdaler/crud/pkg/types"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseSort splits "sort" query param like "-price" into column and direction.
+func parseSort(value string, validation *types.ValidationError, allowed ...string) (string, bool) {
+	desc := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+	if value == "" || value == types.SortID {
+		return value, desc
+	}
+	for _, item := range allowed {
+		if item == value {
+			return value, desc
+		}
+	}
+	validation.Add("sort", "must be one of id, "+strings.Join(allowed, ", "))
+	return "", false
+}
+
+func parseInt(r *http.Request, name string, validation *types.ValidationError) int {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		validation.Add(name, "must be a non-negative integer")
+		return 0
+	}
+	return parsed
+}
+
+func parseBool(r *http.Request, name string, validation *types.ValidationError) bool {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		validation.Add(name, "must be true or false")
+		return false
+	}
+	return parsed
+}
+
+func parseProductFilter(r *http.Request) (*types.ProductFilter, error) {
+	query := r.URL.Query()
+	validation := &types.ValidationError{}
+	filter := &types.ProductFilter{
+		Limit:        parseInt(r, "limit", validation),
+		After:        query.Get("after"),
+		MinPrice:     parseInt(r, "min_price", validation),
+		MaxPrice:     parseInt(r, "max_price", validation),
+		InStock:      parseBool(r, "in_stock", validation),
+		Name:         strings.TrimSpace(query.Get("name")),
+		WithInactive: parseBool(r, "with_inactive", validation),
+		CategoryID:   parseID(r, "category_id", validation),
+	}
+	filter.Sort, filter.Desc = parseSort(query.Get("sort"), validation, types.SortName, types.SortPrice, types.SortCreated)
+	if filter.MaxPrice > 0 && filter.MinPrice > filter.MaxPrice {
+		validation.Add("min_price", "must not be greater than max_price")
+	}
+
+	if err := validation.Err(); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+func parseCustomerFilter(r *http.Request) (*types.CustomerFilter, error) {
+	query := r.URL.Query()
+	validation := &types.ValidationError{}
+	filter := &types.CustomerFilter{
+		Limit: parseInt(r, "limit", validation),
+		After: query.Get("after"),
+		Name:  strings.TrimSpace(query.Get("name")),
+	}
+	filter.Sort, filter.Desc = parseSort(query.Get("sort"), validation, types.SortName, types.SortCreated)
+
+	if err := validation.Err(); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// parseTime accepts RFC3339 timestamp or date, for dates endOfDay moves value
+// to the start of the next day so that it can be used as exclusive upper bound.
+func parseTime(r *http.Request, name string, endOfDay bool, validation *types.ValidationError) time.Time {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed.UTC()
+	}
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		validation.Add(name, "must be a date (2006-01-02) or RFC3339 timestamp")
+		return time.Time{}
+	}
+	if endOfDay {
+		parsed = parsed.AddDate(0, 0, 1)
+	}
+	return parsed
+}
+
+func parseID(r *http.Request, name string, validation *types.ValidationError) int64 {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || parsed <= 0 {
+		validation.Add(name, "must be a positive integer")
+		return 0
+	}
+	return parsed
+}
+
+func parseSaleFilter(r *http.Request) (*types.SaleFilter, error) {
+	validation := &types.ValidationError{}
+	filter := &types.SaleFilter{
+		Limit:      parseInt(r, "limit", validation),
+		After:      r.URL.Query().Get("after"),
+		From:       parseTime(r, "from", false, validation),
+		To:         parseTime(r, "to", true, validation),
+		CustomerID: parseID(r, "customer_id", validation),
+		ManagerID:  parseID(r, "manager_id", validation),
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		validation.Add("from", "must be before to")
+	}
+
+	if err := validation.Err(); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
@@ -3,31 +3,19 @@ package app
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/bdaler/crud/cmd/app/middleawre"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/bdaler/crud/pkg/receipts"
 	"github.com/bdaler/crud/pkg/types"
 	"github.com/gorilla/mux"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
-const ADMIN = "ADMIN"
-
 func (s *Server) handleManagerRegistration(w http.ResponseWriter, r *http.Request) {
-	id, err := middleware.Authentication(r.Context())
-	if err != nil {
-		errorWriter(w, http.StatusBadRequest, err)
-		return
-	}
-	if id == 0 {
-		errorWriter(w, http.StatusForbidden, err)
-		return
-	}
-
-	if !s.managerSvc.IsAdmin(r.Context(), id) {
-		errorWriter(w, http.StatusForbidden, err)
-		return
-	}
-
 	var regItem struct {
 		ID    int64    `json:"id"`
 		Name  string   `json:"name"`
@@ -35,9 +23,21 @@ func (s *Server) handleManagerRegistration(w http.ResponseWriter, r *http.Reques
 		Roles []string `json:"roles"`
 	}
 
-	err = json.NewDecoder(r.Body).Decode(&regItem)
+	err := json.NewDecoder(r.Body).Decode(&regItem)
 	if err != nil {
-		errorWriter(w, http.StatusInternalServerError, err)
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	validation := &types.ValidationError{}
+	if strings.TrimSpace(regItem.Name) == "" {
+		validation.Add("name", "required")
+	}
+	if strings.TrimSpace(regItem.Phone) == "" {
+		validation.Add("phone", "required")
+	}
+	if err = validation.Err(); err != nil {
+		serviceErrorWriter(w, err)
 		return
 	}
 
@@ -45,18 +45,12 @@ func (s *Server) handleManagerRegistration(w http.ResponseWriter, r *http.Reques
 		ID:    regItem.ID,
 		Name:  regItem.Name,
 		Phone: regItem.Phone,
-	}
-
-	for _, role := range regItem.Roles {
-		if role == ADMIN {
-			item.IsAdmin = true
-			break
-		}
+		Roles: regItem.Roles,
 	}
 
 	token, err := s.managerSvc.Create(r.Context(), item)
 	if err != nil {
-		errorWriter(w, http.StatusInternalServerError, err)
+		serviceErrorWriter(w, err)
 		return
 	}
 
@@ -67,13 +61,13 @@ func (s *Server) handleManagerGetToken(w http.ResponseWriter, r *http.Request) {
 	var manager *types.Manager
 	err := json.NewDecoder(r.Body).Decode(&manager)
 	if err != nil {
-		errorWriter(w, http.StatusInternalServerError, err)
+		errorWriter(w, http.StatusBadRequest, err)
 		return
 	}
 
 	token, err := s.managerSvc.Token(r.Context(), manager.Phone, manager.Password)
 	if err != nil {
-		errorWriter(w, http.StatusBadRequest, err)
+		serviceErrorWriter(w, err)
 		return
 	}
 
@@ -83,24 +77,20 @@ func (s *Server) handleManagerGetToken(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleManagerChangeProducts(w http.ResponseWriter, r *http.Request) {
 	id, err := middleware.Authentication(r.Context())
 	if err != nil {
-		errorWriter(w, http.StatusBadRequest, err)
+		errorWriter(w, http.StatusUnauthorized, err)
 		return
 	}
 
-	if id == 0 {
-		errorWriter(w, http.StatusForbidden, err)
-		return
-	}
 	product := &types.Product{}
 	err = json.NewDecoder(r.Body).Decode(&product)
 	if err != nil {
-		errorWriter(w, http.StatusInternalServerError, err)
+		errorWriter(w, http.StatusBadRequest, err)
 		return
 	}
 
-	product, err = s.managerSvc.SaveProduct(r.Context(), product)
+	product, err = s.managerSvc.SaveProduct(r.Context(), product, id)
 	if err != nil {
-		errorWriter(w, http.StatusInternalServerError, err)
+		serviceErrorWriter(w, err)
 		return
 	}
 
@@ -109,170 +99,743 @@ func (s *Server) handleManagerChangeProducts(w http.ResponseWriter, r *http.Requ
 
 func (s *Server) handleManagerMakeSales(w http.ResponseWriter, r *http.Request) {
 	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	sale := &types.Sale{}
+	err = json.NewDecoder(r.Body).Decode(&sale)
 	if err != nil {
 		errorWriter(w, http.StatusBadRequest, err)
 		return
 	}
+	sale.ManagerID = id
 
-	if id == 0 {
-		errorWriter(w, http.StatusForbidden, err)
+	sale, err = s.managerSvc.MakeSale(r.Context(), sale)
+	if err != nil {
+		serviceErrorWriter(w, err)
 		return
 	}
 
-	sale := &types.Sale{}
-	sale.ManagerID = id
-	err = json.NewDecoder(r.Body).Decode(&sale)
+	responseJSON(w, sale)
+}
 
+func (s *Server) handleManagerGetSales(w http.ResponseWriter, r *http.Request) {
+	id, err := middleware.Authentication(r.Context())
 	if err != nil {
-		errorWriter(w, http.StatusInternalServerError, err)
+		errorWriter(w, http.StatusUnauthorized, err)
 		return
 	}
 
-	sale, err = s.managerSvc.MakeSale(r.Context(), sale)
+	totals, err := s.managerSvc.GetSales(r.Context(), id)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, map[string]interface{}{"manager_id": id, "totals": totals})
+}
+
+func (s *Server) handleManagerListSales(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseSaleFilter(r)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	page, err := s.managerSvc.Sales(r.Context(), filter)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, page)
+}
+
+func (s *Server) handleManagerGetSaleByID(w http.ResponseWriter, r *http.Request) {
+	saleID, err := pathID(r)
 	if err != nil {
 		errorWriter(w, http.StatusBadRequest, err)
 		return
 	}
 
+	sale, err := s.managerSvc.SaleByID(r.Context(), saleID)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
 	responseJSON(w, sale)
 }
 
-func (s *Server) handleManagerGetSales(w http.ResponseWriter, r *http.Request) {
+// handleManagerGetReceipt renders receipt of the sale as text (default) or pdf, see format parameter.
+func (s *Server) handleManagerGetReceipt(w http.ResponseWriter, r *http.Request) {
+	saleID, err := pathID(r)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "text" && format != "pdf" {
+		validation := &types.ValidationError{}
+		validation.Add("format", "must be one of text, pdf")
+		serviceErrorWriter(w, validation)
+		return
+	}
+
+	receipt, err := s.managerSvc.Receipt(r.Context(), saleID)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	data := receipts.Text(receipt)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if format == "pdf" {
+		data = receipts.PDF(receipt)
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="receipt-%d.pdf"`, saleID))
+	}
+	if _, err = w.Write(data); err != nil {
+		logger.Errorf("response write error: %v", err)
+	}
+}
+
+func (s *Server) handleManagerAssignSale(w http.ResponseWriter, r *http.Request) {
 	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	saleID, err := pathID(r)
 	if err != nil {
 		errorWriter(w, http.StatusBadRequest, err)
 		return
 	}
 
-	if id == 0 {
-		//вызываем фукцию для ответа с ошибкой
-		errorWriter(w, http.StatusForbidden, err)
+	sale, err := s.managerSvc.AssignSale(r.Context(), saleID, id)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, sale)
+}
+
+func (s *Server) handleManagerPaySale(w http.ResponseWriter, r *http.Request) {
+	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
 		return
 	}
 
-	total, err := s.managerSvc.GetSales(r.Context(), id)
+	saleID, err := pathID(r)
 	if err != nil {
 		errorWriter(w, http.StatusBadRequest, err)
 		return
 	}
 
-	responseJSON(w, map[string]interface{}{"manager_id": id, "total": total})
+	var item struct {
+		Payments []*types.Payment `json:"payments"`
+	}
+	if err = json.NewDecoder(r.Body).Decode(&item); err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sale, err := s.managerSvc.PaySale(r.Context(), saleID, id, item.Payments)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, sale)
 }
 
-func (s *Server) handleManagerGetProducts(w http.ResponseWriter, r *http.Request) {
-	items, err := s.managerSvc.Products(r.Context())
+func (s *Server) handleManagerVoidSale(w http.ResponseWriter, r *http.Request) {
+	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	saleID, err := pathID(r)
 	if err != nil {
 		errorWriter(w, http.StatusBadRequest, err)
 		return
 	}
 
-	responseJSON(w, items)
+	sale, err := s.managerSvc.VoidSale(r.Context(), saleID, id)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, sale)
 }
 
-func (s *Server) handleManagerRemoveProductByID(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleManagerMakeReturn(w http.ResponseWriter, r *http.Request) {
 	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	saleID, err := pathID(r)
 	if err != nil {
 		errorWriter(w, http.StatusBadRequest, err)
 		return
 	}
 
-	if id == 0 {
-		errorWriter(w, http.StatusForbidden, err)
+	ret := &types.Return{}
+	err = json.NewDecoder(r.Body).Decode(&ret)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
 		return
 	}
+	ret.SaleID = saleID
+	ret.ManagerID = id
 
-	idParam, ok := mux.Vars(r)["id"]
-	if !ok {
-		errorWriter(w, http.StatusBadRequest, errors.New("id required"))
+	ret, err = s.managerSvc.MakeReturn(r.Context(), ret)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, ret)
+}
+
+func (s *Server) handleManagerGetReturns(w http.ResponseWriter, r *http.Request) {
+	saleID, err := pathID(r)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	items, err := s.managerSvc.Returns(r.Context(), saleID)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, items)
+}
+
+func (s *Server) handleManagerReserve(w http.ResponseWriter, r *http.Request) {
+	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
 		return
 	}
-	productID, err := strconv.ParseInt(idParam, 10, 64)
+
+	reservation := &types.Reservation{}
+	err = json.NewDecoder(r.Body).Decode(&reservation)
 	if err != nil {
 		errorWriter(w, http.StatusBadRequest, err)
 		return
 	}
+	reservation.ManagerID = id
 
-	err = s.managerSvc.RemoveProductByID(r.Context(), productID)
+	reservation, err = s.managerSvc.Reserve(r.Context(), reservation)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, reservation)
+}
+
+func (s *Server) handleManagerGetReservation(w http.ResponseWriter, r *http.Request) {
+	reservationID, err := pathID(r)
 	if err != nil {
 		errorWriter(w, http.StatusBadRequest, err)
 		return
 	}
+
+	reservation, err := s.managerSvc.Reservation(r.Context(), reservationID)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, reservation)
 }
 
-func (s *Server) handleManagerRemoveCustomerByID(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleManagerReleaseReservation(w http.ResponseWriter, r *http.Request) {
+	reservationID, err := pathID(r)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err = s.managerSvc.ReleaseReservation(r.Context(), reservationID); err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleManagerConfirmReservation(w http.ResponseWriter, r *http.Request) {
 	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	reservationID, err := pathID(r)
 	if err != nil {
 		errorWriter(w, http.StatusBadRequest, err)
 		return
 	}
 
-	if id == 0 {
-		errorWriter(w, http.StatusForbidden, err)
+	sale, err := s.managerSvc.ConfirmReservation(r.Context(), reservationID, id)
+	if err != nil {
+		serviceErrorWriter(w, err)
 		return
 	}
 
-	idParam, ok := mux.Vars(r)["id"]
-	if !ok {
-		errorWriter(w, http.StatusBadRequest, errors.New("Missing id"))
+	responseJSON(w, sale)
+}
+
+func (s *Server) handleManagerGetProducts(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseProductFilter(r)
+	if err != nil {
+		serviceErrorWriter(w, err)
 		return
 	}
 
-	customerID, err := strconv.ParseInt(idParam, 10, 64)
+	items, err := s.managerSvc.Products(r.Context(), filter)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, items)
+}
+
+func (s *Server) handleManagerGetLowStock(w http.ResponseWriter, r *http.Request) {
+	items, err := s.managerSvc.LowStockProducts(r.Context())
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, items)
+}
+
+func (s *Server) handleManagerGetProductByID(w http.ResponseWriter, r *http.Request) {
+	productID, err := pathID(r)
 	if err != nil {
 		errorWriter(w, http.StatusBadRequest, err)
 		return
 	}
 
-	err = s.managerSvc.RemoveCustomerByID(r.Context(), customerID)
+	product, err := s.managerSvc.ProductByID(r.Context(), productID)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, product)
+}
+
+func (s *Server) handleManagerRemoveProductByID(w http.ResponseWriter, r *http.Request) {
+	productID, err := pathID(r)
 	if err != nil {
 		errorWriter(w, http.StatusBadRequest, err)
 		return
 	}
+
+	product, err := s.managerSvc.RemoveProductByID(r.Context(), productID)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, product)
 }
 
-func (s *Server) handleManagerGetCustomers(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleManagerRestoreProductByID(w http.ResponseWriter, r *http.Request) {
+	productID, err := pathID(r)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	product, err := s.managerSvc.RestoreProductByID(r.Context(), productID)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, product)
+}
+
+func (s *Server) handleManagerPurgeProductByID(w http.ResponseWriter, r *http.Request) {
+	productID, err := pathID(r)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = s.managerSvc.PurgeProductByID(r.Context(), productID)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleManagerMoveStock(w http.ResponseWriter, r *http.Request) {
 	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	productID, err := pathID(r)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var item struct {
+		Kind   string `json:"kind"`
+		Qty    int    `json:"qty"`
+		Reason string `json:"reason"`
+	}
+	if err = json.NewDecoder(r.Body).Decode(&item); err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	movement, err := s.managerSvc.MoveStock(r.Context(), &types.StockMovement{
+		ProductID: productID,
+		Kind:      item.Kind,
+		Qty:       item.Qty,
+		ManagerID: id,
+		Reason:    item.Reason,
+	})
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, movement)
+}
+
+func (s *Server) handleManagerGetMovements(w http.ResponseWriter, r *http.Request) {
+	productID, err := pathID(r)
 	if err != nil {
 		errorWriter(w, http.StatusBadRequest, err)
 		return
 	}
 
-	if id == 0 {
-		errorWriter(w, http.StatusForbidden, err)
+	validation := &types.ValidationError{}
+	filter := &types.MovementFilter{
+		ProductID: productID,
+		Limit:     parseInt(r, "limit", validation),
+		After:     r.URL.Query().Get("after"),
+	}
+	if err = validation.Err(); err != nil {
+		serviceErrorWriter(w, err)
 		return
 	}
 
-	items, err := s.managerSvc.Customers(r.Context())
+	page, err := s.managerSvc.Movements(r.Context(), filter)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, page)
+}
+
+func (s *Server) handleManagerGetPrices(w http.ResponseWriter, r *http.Request) {
+	productID, err := pathID(r)
 	if err != nil {
 		errorWriter(w, http.StatusBadRequest, err)
 		return
 	}
 
+	items, err := s.managerSvc.ProductPrices(r.Context(), productID)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
 	responseJSON(w, items)
 }
 
-func (s *Server) handleManagerChangeCustomer(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleManagerGetPriceAt(w http.ResponseWriter, r *http.Request) {
+	productID, err := pathID(r)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	validation := &types.ValidationError{}
+	at := parseTime(r, "at", false, validation)
+	if err = validation.Err(); err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	price, err := s.managerSvc.PriceAt(r.Context(), productID, at)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, price)
+}
+
+func (s *Server) handleManagerSchedulePrice(w http.ResponseWriter, r *http.Request) {
 	id, err := middleware.Authentication(r.Context())
+	if err != nil {
+		errorWriter(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	productID, err := pathID(r)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var item struct {
+		Price         types.Money `json:"price"`
+		EffectiveFrom time.Time   `json:"effective_from"`
+		EffectiveTo   *time.Time  `json:"effective_to"`
+	}
+	if err = json.NewDecoder(r.Body).Decode(&item); err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	price, err := s.managerSvc.SchedulePrice(r.Context(), &types.ProductPrice{
+		ProductID:     productID,
+		Price:         item.Price,
+		EffectiveFrom: item.EffectiveFrom,
+		EffectiveTo:   item.EffectiveTo,
+		ManagerID:     id,
+	})
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, price)
+}
+
+func (s *Server) handleManagerCancelPrice(w http.ResponseWriter, r *http.Request) {
+	productID, err := pathID(r)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+	priceID, err := strconv.ParseInt(mux.Vars(r)["priceId"], 10, 64)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err = s.managerSvc.CancelPrice(r.Context(), productID, priceID); err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleManagerGetStockMismatches(w http.ResponseWriter, r *http.Request) {
+	items, err := s.managerSvc.StockMismatches(r.Context())
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, map[string]interface{}{"consistent": len(items) == 0, "mismatches": items})
+}
+
+func (s *Server) handleManagerGetCategories(w http.ResponseWriter, r *http.Request) {
+	items, err := s.managerSvc.Categories(r.Context())
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, items)
+}
+
+func (s *Server) handleManagerGetPromos(w http.ResponseWriter, r *http.Request) {
+	items, err := s.managerSvc.Promos(r.Context())
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, items)
+}
+
+func (s *Server) handleManagerGetPromoByID(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	item, err := s.managerSvc.PromoByID(r.Context(), id)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, item)
+}
+
+func (s *Server) handleManagerChangePromo(w http.ResponseWriter, r *http.Request) {
+	// new codes are active unless told otherwise
+	promo := &types.PromoCode{Active: true}
+	err := json.NewDecoder(r.Body).Decode(&promo)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	promo, err = s.managerSvc.SavePromo(r.Context(), promo)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, promo)
+}
+
+func (s *Server) handleManagerChangeCategory(w http.ResponseWriter, r *http.Request) {
+	category := &types.Category{}
+	err := json.NewDecoder(r.Body).Decode(&category)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	category, err = s.managerSvc.SaveCategory(r.Context(), category)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, category)
+}
+
+func (s *Server) handleManagerRemoveCategory(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := pathID(r)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err = s.managerSvc.RemoveCategory(r.Context(), categoryID); err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleManagerGetProductCategories(w http.ResponseWriter, r *http.Request) {
+	productID, err := pathID(r)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	items, err := s.managerSvc.ProductCategories(r.Context(), productID)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, items)
+}
+
+func (s *Server) handleManagerSetProductCategories(w http.ResponseWriter, r *http.Request) {
+	productID, err := pathID(r)
+	if err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var item struct {
+		CategoryIDs []int64 `json:"category_ids"`
+	}
+	if err = json.NewDecoder(r.Body).Decode(&item); err != nil {
+		errorWriter(w, http.StatusBadRequest, err)
+		return
+	}
+
+	items, err := s.managerSvc.SetProductCategories(r.Context(), productID, item.CategoryIDs)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, items)
+}
+
+func (s *Server) handleManagerRemoveCustomerByID(w http.ResponseWriter, r *http.Request) {
+	idParam, ok := mux.Vars(r)["id"]
+	if !ok {
+		errorWriter(w, http.StatusBadRequest, errors.New("Missing id"))
+		return
+	}
+
+	customerID, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
 		errorWriter(w, http.StatusBadRequest, err)
 		return
 	}
 
-	if id == 0 {
-		errorWriter(w, http.StatusForbidden, err)
+	err = s.managerSvc.RemoveCustomerByID(r.Context(), customerID)
+	if err != nil {
+		serviceErrorWriter(w, err)
 		return
 	}
+}
 
+func (s *Server) handleManagerGetCustomers(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseCustomerFilter(r)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	items, err := s.managerSvc.Customers(r.Context(), filter)
+	if err != nil {
+		serviceErrorWriter(w, err)
+		return
+	}
+
+	responseJSON(w, items)
+}
+
+func (s *Server) handleManagerChangeCustomer(w http.ResponseWriter, r *http.Request) {
 	customer := &types.Customer{}
-	err = json.NewDecoder(r.Body).Decode(&customer)
+	err := json.NewDecoder(r.Body).Decode(&customer)
 	if err != nil {
-		errorWriter(w, http.StatusInternalServerError, err)
+		errorWriter(w, http.StatusBadRequest, err)
 		return
 	}
 
 	customer, err = s.managerSvc.ChangeCustomer(r.Context(), customer)
 	if err != nil {
-		errorWriter(w, http.StatusBadRequest, err)
+		serviceErrorWriter(w, err)
 		return
 	}
 
@@ -3,40 +3,47 @@ package middleware
 import (
 	"context"
 	"errors"
+	"github.com/bdaler/crud/pkg/rest"
+	"github.com/bdaler/crud/pkg/types"
 	"net/http"
 )
 
 const (
-	MANAGER = "MANAGER"
-	ADMIN   = "ADMIN"
+	MANAGER = types.RoleManager
+	ADMIN   = types.RoleAdmin
 )
 
-var ErrNoAuthentication = errors.New("no authentication")
+var ErrNoAuthentication = types.ErrNoAuthentication
+var ErrForbidden = errors.New("not enough permissions")
 var authenticationContextKey = &contextKey{"authentication context"}
+var tokenExpiredContextKey = &contextKey{"token expired context"}
 
 type contextKey struct {
 	name string
 }
 
 type IDFunc func(ctx context.Context, token string) (int64, error)
-type HasAnyRoleFunc func(ctx context.Context, roles ...string) bool
+type HasAnyRoleFunc func(ctx context.Context, roles ...string) (bool, error)
 
 func (c *contextKey) String() string {
 	return c.name
 }
 
+// Authenticate lets requests with expired tokens pass as anonymous, so that
+// the token can be renewed, RequireAuthentication and RequireRoles reject them.
 func Authenticate(idFunc IDFunc) func(http.Handler) http.Handler {
 	return func(handler http.Handler) http.Handler {
 		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 			token := request.Header.Get("Authorization")
 			id, err := idFunc(request.Context(), token)
-
-			if err != nil {
-				http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			expired := errors.Is(err, types.ErrTokenExpired)
+			if err != nil && !expired {
+				rest.WriteError(writer, http.StatusInternalServerError, err)
 				return
 			}
 
 			ctx := context.WithValue(request.Context(), authenticationContextKey, id)
+			ctx = context.WithValue(ctx, tokenExpiredContextKey, expired)
 			request = request.WithContext(ctx)
 			handler.ServeHTTP(writer, request)
 		})
@@ -49,3 +56,52 @@ func Authentication(ctx context.Context) (int64, error) {
 	}
 	return 0, ErrNoAuthentication
 }
+
+// unauthenticated tells clients with expired tokens to renew them.
+func unauthenticated(writer http.ResponseWriter, request *http.Request) {
+	if expired, _ := request.Context().Value(tokenExpiredContextKey).(bool); expired {
+		rest.WriteError(writer, http.StatusUnauthorized, types.ErrTokenExpired)
+		return
+	}
+	rest.WriteError(writer, http.StatusUnauthorized, ErrNoAuthentication)
+}
+
+// RequireAuthentication rejects anonymous requests with 401,
+// it must be attached after Authenticate.
+func RequireAuthentication(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		id, err := Authentication(request.Context())
+		if err != nil || id == 0 {
+			unauthenticated(writer, request)
+			return
+		}
+
+		handler.ServeHTTP(writer, request)
+	})
+}
+
+// RequireRoles must be attached after Authenticate: anonymous requests get 401,
+// authenticated ones without any of the roles get 403 and failed role checks 500.
+func RequireRoles(hasAnyRole HasAnyRoleFunc, roles ...string) func(http.Handler) http.Handler {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			id, err := Authentication(request.Context())
+			if err != nil || id == 0 {
+				unauthenticated(writer, request)
+				return
+			}
+
+			has, err := hasAnyRole(request.Context(), roles...)
+			if err != nil {
+				rest.WriteError(writer, http.StatusInternalServerError, err)
+				return
+			}
+			if !has {
+				rest.WriteError(writer, http.StatusForbidden, ErrForbidden)
+				return
+			}
+
+			handler.ServeHTTP(writer, request)
+		})
+	}
+}
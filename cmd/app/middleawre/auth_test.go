@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/bdaler/crud/pkg/rest"
+	"github.com/bdaler/crud/pkg/types"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireRoles(t *testing.T) {
+	ok := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {})
+	idFunc := func(ctx context.Context, token string) (int64, error) {
+		if token == "" {
+			return 0, nil
+		}
+		return 1, nil
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		has    bool
+		err    error
+		status int
+	}{
+		{"anonymous", "", true, nil, http.StatusUnauthorized},
+		{"has role", "token", true, nil, http.StatusOK},
+		{"no role", "token", false, nil, http.StatusForbidden},
+		{"check failed", "token", false, types.ErrInternal, http.StatusInternalServerError},
+		{"unexpected error", "token", false, errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasAnyRole := func(ctx context.Context, roles ...string) (bool, error) {
+				return tt.has, tt.err
+			}
+			handler := Authenticate(idFunc)(RequireRoles(hasAnyRole, ADMIN)(ok))
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.Header.Set("Authorization", tt.token)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.status {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestAuthenticate_expired(t *testing.T) {
+	ok := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {})
+	idFunc := func(ctx context.Context, token string) (int64, error) {
+		switch token {
+		case "expired":
+			return 0, types.ErrTokenExpired
+		case "":
+			return 0, nil
+		}
+		return 1, nil
+	}
+	hasAnyRole := func(ctx context.Context, roles ...string) (bool, error) {
+		return true, nil
+	}
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		token   string
+		status  int
+		code    string
+	}{
+		{"token with expired header", ok, "expired", http.StatusOK, ""},
+		{"authentication expired", RequireAuthentication(ok), "expired", http.StatusUnauthorized, "token_expired"},
+		{"authentication anonymous", RequireAuthentication(ok), "", http.StatusUnauthorized, "no_authentication"},
+		{"roles expired", RequireRoles(hasAnyRole, ADMIN)(ok), "expired", http.StatusUnauthorized, "token_expired"},
+		{"roles valid", RequireRoles(hasAnyRole, ADMIN)(ok), "token", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.Handle("/token", Authenticate(idFunc)(tt.handler))
+
+			request := httptest.NewRequest(http.MethodPost, "/token", nil)
+			request.Header.Set("Authorization", tt.token)
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.status {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.status)
+			}
+			if tt.code == "" {
+				return
+			}
+			var resp rest.ErrorResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Code != tt.code {
+				t.Errorf("code = %q, want %q", resp.Code, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CORS allows cross-origin requests from given origins, "*" allows any origin.
+// It must wrap the whole router, otherwise preflight OPTIONS requests never reach it.
+func CORS(origins []string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowed[origin] = true
+	}
+
+	return func(handler http.Handler) http.Handler {
+		if len(allowed) == 0 {
+			return handler
+		}
+
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			origin := request.Header.Get("Origin")
+			if origin == "" || !(allowed["*"] || allowed[origin]) {
+				handler.ServeHTTP(writer, request)
+				return
+			}
+
+			header := writer.Header()
+			header.Set("Access-Control-Allow-Origin", origin)
+			header.Add("Vary", "Origin")
+			header.Set("Access-Control-Expose-Headers", "X-Request-ID")
+
+			if request.Method == http.MethodOptions && request.Header.Get("Access-Control-Request-Method") != "" {
+				header.Set("Access-Control-Allow-Methods", strings.Join([]string{
+					http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete,
+				}, ", "))
+				header.Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Request-ID")
+				header.Set("Access-Control-Max-Age", "600")
+				writer.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			handler.ServeHTTP(writer, request)
+		})
+	}
+}
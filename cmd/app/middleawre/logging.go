@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"github.com/bdaler/crud/pkg/logger"
+	"net/http"
+	"time"
+)
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Logging writes every request with its status and duration on debug level,
+// it must be wrapped by RequestID.
+func Logging(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		started := time.Now()
+		sw := &statusWriter{ResponseWriter: writer, status: http.StatusOK}
+		handler.ServeHTTP(sw, request)
+		logger.Debugf("%s %s %d %s %s",
+			request.Method, request.URL.Path, sw.status, time.Since(started), RequestIDFrom(request.Context()))
+	})
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/bdaler/crud/pkg/rest"
+	"net/http"
+)
+
+var requestIDContextKey = &contextKey{"request id context"}
+
+// RequestID keeps an incoming X-Request-ID or generates a new one and
+// echoes it back in the response headers, error bodies pick it up from there.
+// It must wrap the whole router, handlers get the id with RequestIDFrom.
+func RequestID(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		id := request.Header.Get(rest.RequestIDHeader)
+		if id == "" || len(id) > 64 {
+			id = newRequestID()
+		}
+
+		writer.Header().Set(rest.RequestIDHeader, id)
+		ctx := context.WithValue(request.Context(), requestIDContextKey, id)
+		handler.ServeHTTP(writer, request.WithContext(ctx))
+	})
+}
+
+func RequestIDFrom(ctx context.Context) string {
+	if value, ok := ctx.Value(requestIDContextKey).(string); ok {
+		return value
+	}
+	return ""
+}
+
+func newRequestID() string {
+	buffer := make([]byte, 16)
+	if _, err := rand.Read(buffer); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buffer)
+}
@@ -1,35 +1,52 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"github.com/bdaler/crud/cmd/app/middleawre"
+	"github.com/bdaler/crud/pkg/config"
 	"github.com/bdaler/crud/pkg/customers"
+	"github.com/bdaler/crud/pkg/logger"
 	"github.com/bdaler/crud/pkg/managers"
+	"github.com/bdaler/crud/pkg/rest"
 	"github.com/gorilla/mux"
-	"log"
 	"net/http"
+	"strconv"
 )
 
 type Server struct {
 	mux         *mux.Router
+	handler     http.Handler
+	cfg         *config.Config
 	customerSvc *customers.Service
 	managerSvc  *managers.Service
 }
 
-func NewServer(m *mux.Router, cSvc *customers.Service, mSvc *managers.Service) *Server {
+func NewServer(m *mux.Router, cfg *config.Config, cSvc *customers.Service, mSvc *managers.Service) *Server {
 	return &Server{
 		mux:         m,
+		handler:     m,
+		cfg:         cfg,
 		customerSvc: cSvc,
 		managerSvc:  mSvc,
 	}
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	s.mux.ServeHTTP(w, r)
+	s.handler.ServeHTTP(w, r)
 }
 
 func (s *Server) Init() {
-	log.Println("start init method")
+	// request id wraps everything, so that logs and errors of unmatched routes have it too
+	s.handler = middleware.RequestID(middleware.Logging(middleware.CORS(s.cfg.CORSOrigins)(s.mux)))
+	s.mux.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		errorWriter(w, http.StatusNotFound, nil)
+	})
+	s.mux.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		errorWriter(w, http.StatusMethodNotAllowed, nil)
+	})
+
 	customersAuthenticateMd := middleware.Authenticate(s.customerSvc.IDByToken)
 	customersSubrouter := s.mux.PathPrefix("/api/customers").Subrouter()
 	customersSubrouter.Use(customersAuthenticateMd)
@@ -37,26 +54,98 @@ func (s *Server) Init() {
 	customersSubrouter.HandleFunc("", s.handleCustomerRegistration).Methods("POST")
 	customersSubrouter.HandleFunc("/token", s.handleCustomerGetToken).Methods("POST")
 	customersSubrouter.HandleFunc("/products", s.handleCustomerGetProducts).Methods("GET")
+	customersSubrouter.HandleFunc("/products/search", s.handleCustomerSearchProducts).Methods("GET")
+	customersSubrouter.HandleFunc("/categories", s.handleCustomerGetCategories).Methods("GET")
+
+	customerMd := middleware.RequireAuthentication
+	customersSubrouter.Handle("/me", customerMd(http.HandlerFunc(s.handleCustomerGetMe))).Methods("GET")
+	customersSubrouter.Handle("/me", customerMd(http.HandlerFunc(s.handleCustomerChangeMe))).Methods("PUT")
+	customersSubrouter.Handle("/me/purchases", customerMd(http.HandlerFunc(s.handleCustomerGetPurchases))).Methods("GET")
+	customersSubrouter.Handle("/cart", customerMd(http.HandlerFunc(s.handleCustomerGetCart))).Methods("GET")
+	customersSubrouter.Handle("/cart", customerMd(http.HandlerFunc(s.handleCustomerClearCart))).Methods("DELETE")
+	customersSubrouter.Handle("/cart/items/{id:[0-9]+}", customerMd(http.HandlerFunc(s.handleCustomerSetCartItem))).Methods("PUT")
+	customersSubrouter.Handle("/cart/items/{id:[0-9]+}", customerMd(http.HandlerFunc(s.handleCustomerRemoveCartItem))).Methods("DELETE")
+	customersSubrouter.Handle("/cart/checkout", customerMd(http.HandlerFunc(s.handleCustomerCheckout))).Methods("POST")
+	customersSubrouter.Handle("/reservations", customerMd(http.HandlerFunc(s.handleCustomerReserve))).Methods("POST")
+	customersSubrouter.Handle("/reservations/{id:[0-9]+}", customerMd(http.HandlerFunc(s.handleCustomerGetReservation))).Methods("GET")
+	customersSubrouter.Handle("/reservations/{id:[0-9]+}", customerMd(http.HandlerFunc(s.handleCustomerReleaseReservation))).Methods("DELETE")
+	customersSubrouter.Handle("/reservations/{id:[0-9]+}/confirm", customerMd(http.HandlerFunc(s.handleCustomerConfirmReservation))).Methods("POST")
 
 	managersAuthenticateMd := middleware.Authenticate(s.managerSvc.IDByToken)
 	managersSubRouter := s.mux.PathPrefix("/api/managers").Subrouter()
 	managersSubRouter.Use(managersAuthenticateMd)
-	managersSubRouter.HandleFunc("", s.handleManagerRegistration).Methods("POST")
+
+	managerMd := middleware.RequireRoles(s.managerHasAnyRole, middleware.MANAGER, middleware.ADMIN)
+	adminMd := middleware.RequireRoles(s.managerHasAnyRole, middleware.ADMIN)
+
+	managersSubRouter.Handle("", adminMd(http.HandlerFunc(s.handleManagerRegistration))).Methods("POST")
 	managersSubRouter.HandleFunc("/token", s.handleManagerGetToken).Methods("POST")
-	managersSubRouter.HandleFunc("/sales", s.handleManagerGetSales).Methods("GET")
-	managersSubRouter.HandleFunc("/sales", s.handleManagerMakeSales).Methods("POST")
-	managersSubRouter.HandleFunc("/products", s.handleManagerGetProducts).Methods("GET")
-	managersSubRouter.HandleFunc("/products", s.handleManagerChangeProducts).Methods("POST")
-	managersSubRouter.HandleFunc("/products/{id:[0-9]+}", s.handleManagerRemoveProductByID).Methods("DELETE")
-	managersSubRouter.HandleFunc("/customers", s.handleManagerGetCustomers).Methods("GET")
-	managersSubRouter.HandleFunc("/customers", s.handleManagerChangeCustomer).Methods("POST")
-	managersSubRouter.HandleFunc("/customers/{id:[0-9]+}", s.handleManagerRemoveCustomerByID).Methods("DELETE")
+	managersSubRouter.Handle("/sales", managerMd(http.HandlerFunc(s.handleManagerGetSales))).Methods("GET")
+	managersSubRouter.Handle("/sales", managerMd(http.HandlerFunc(s.handleManagerMakeSales))).Methods("POST")
+	managersSubRouter.Handle("/sales/list", managerMd(http.HandlerFunc(s.handleManagerListSales))).Methods("GET")
+	managersSubRouter.Handle("/sales/{id:[0-9]+}", managerMd(http.HandlerFunc(s.handleManagerGetSaleByID))).Methods("GET")
+	managersSubRouter.Handle("/sales/{id:[0-9]+}/receipt", managerMd(http.HandlerFunc(s.handleManagerGetReceipt))).Methods("GET")
+	managersSubRouter.Handle("/sales/{id:[0-9]+}/payments", managerMd(http.HandlerFunc(s.handleManagerPaySale))).Methods("POST")
+	managersSubRouter.Handle("/sales/{id:[0-9]+}/void", managerMd(http.HandlerFunc(s.handleManagerVoidSale))).Methods("POST")
+	managersSubRouter.Handle("/sales/{id:[0-9]+}/manager", managerMd(http.HandlerFunc(s.handleManagerAssignSale))).Methods("POST")
+	managersSubRouter.Handle("/sales/{id:[0-9]+}/returns", managerMd(http.HandlerFunc(s.handleManagerGetReturns))).Methods("GET")
+	managersSubRouter.Handle("/sales/{id:[0-9]+}/returns", managerMd(http.HandlerFunc(s.handleManagerMakeReturn))).Methods("POST")
+	managersSubRouter.Handle("/reservations", managerMd(http.HandlerFunc(s.handleManagerReserve))).Methods("POST")
+	managersSubRouter.Handle("/reservations/{id:[0-9]+}", managerMd(http.HandlerFunc(s.handleManagerGetReservation))).Methods("GET")
+	managersSubRouter.Handle("/reservations/{id:[0-9]+}", managerMd(http.HandlerFunc(s.handleManagerReleaseReservation))).Methods("DELETE")
+	managersSubRouter.Handle("/reservations/{id:[0-9]+}/confirm", managerMd(http.HandlerFunc(s.handleManagerConfirmReservation))).Methods("POST")
+	managersSubRouter.Handle("/products", managerMd(http.HandlerFunc(s.handleManagerGetProducts))).Methods("GET")
+	managersSubRouter.Handle("/products", managerMd(http.HandlerFunc(s.handleManagerChangeProducts))).Methods("POST")
+	managersSubRouter.Handle("/products/{id:[0-9]+}/categories", managerMd(http.HandlerFunc(s.handleManagerGetProductCategories))).Methods("GET")
+	managersSubRouter.Handle("/products/{id:[0-9]+}/categories", managerMd(http.HandlerFunc(s.handleManagerSetProductCategories))).Methods("PUT")
+	managersSubRouter.Handle("/promo-codes", managerMd(http.HandlerFunc(s.handleManagerGetPromos))).Methods("GET")
+	managersSubRouter.Handle("/promo-codes", adminMd(http.HandlerFunc(s.handleManagerChangePromo))).Methods("POST")
+	managersSubRouter.Handle("/promo-codes/{id:[0-9]+}", managerMd(http.HandlerFunc(s.handleManagerGetPromoByID))).Methods("GET")
+	managersSubRouter.Handle("/categories", managerMd(http.HandlerFunc(s.handleManagerGetCategories))).Methods("GET")
+	managersSubRouter.Handle("/categories", adminMd(http.HandlerFunc(s.handleManagerChangeCategory))).Methods("POST")
+	managersSubRouter.Handle("/categories/{id:[0-9]+}", adminMd(http.HandlerFunc(s.handleManagerRemoveCategory))).Methods("DELETE")
+	managersSubRouter.Handle("/products/low-stock", managerMd(http.HandlerFunc(s.handleManagerGetLowStock))).Methods("GET")
+	managersSubRouter.Handle("/products/reconciliation", managerMd(http.HandlerFunc(s.handleManagerGetStockMismatches))).Methods("GET")
+	managersSubRouter.Handle("/products/{id:[0-9]+}/movements", managerMd(http.HandlerFunc(s.handleManagerGetMovements))).Methods("GET")
+	managersSubRouter.Handle("/products/{id:[0-9]+}/movements", managerMd(http.HandlerFunc(s.handleManagerMoveStock))).Methods("POST")
+	managersSubRouter.Handle("/products/{id:[0-9]+}/prices", managerMd(http.HandlerFunc(s.handleManagerGetPrices))).Methods("GET")
+	managersSubRouter.Handle("/products/{id:[0-9]+}/prices", managerMd(http.HandlerFunc(s.handleManagerSchedulePrice))).Methods("POST")
+	managersSubRouter.Handle("/products/{id:[0-9]+}/prices/{priceId:[0-9]+}", managerMd(http.HandlerFunc(s.handleManagerCancelPrice))).Methods("DELETE")
+	managersSubRouter.Handle("/products/{id:[0-9]+}/price", managerMd(http.HandlerFunc(s.handleManagerGetPriceAt))).Methods("GET")
+	managersSubRouter.Handle("/products/{id:[0-9]+}", managerMd(http.HandlerFunc(s.handleManagerGetProductByID))).Methods("GET")
+	managersSubRouter.Handle("/products/{id:[0-9]+}", adminMd(http.HandlerFunc(s.handleManagerRemoveProductByID))).Methods("DELETE")
+	managersSubRouter.Handle("/products/{id:[0-9]+}/restore", adminMd(http.HandlerFunc(s.handleManagerRestoreProductByID))).Methods("POST")
+	managersSubRouter.Handle("/products/{id:[0-9]+}/purge", adminMd(http.HandlerFunc(s.handleManagerPurgeProductByID))).Methods("DELETE")
+	managersSubRouter.Handle("/customers", managerMd(http.HandlerFunc(s.handleManagerGetCustomers))).Methods("GET")
+	managersSubRouter.Handle("/customers", managerMd(http.HandlerFunc(s.handleManagerChangeCustomer))).Methods("POST")
+	managersSubRouter.Handle("/customers/{id:[0-9]+}", adminMd(http.HandlerFunc(s.handleManagerRemoveCustomerByID))).Methods("DELETE")
+}
 
+func (s *Server) managerHasAnyRole(ctx context.Context, roles ...string) (bool, error) {
+	id, err := middleware.Authentication(ctx)
+	if err != nil {
+		return false, nil
+	}
+
+	return s.managerSvc.HasAnyRole(ctx, id, roles...)
 }
 
 func errorWriter(w http.ResponseWriter, httpSts int, err error) {
-	log.Println("writeError: ", err)
-	http.Error(w, http.StatusText(httpSts), httpSts)
+	rest.WriteError(w, httpSts, err)
+}
+
+// serviceErrorWriter picks http status by sentinel errors from pkg/types.
+func serviceErrorWriter(w http.ResponseWriter, err error) {
+	rest.WriteError(w, rest.Status(err), err)
+}
+
+// pathID parses {id} route variable.
+func pathID(r *http.Request) (int64, error) {
+	idParam, ok := mux.Vars(r)["id"]
+	if !ok {
+		return 0, errors.New("id required")
+	}
+	return strconv.ParseInt(idParam, 10, 64)
 }
 
 func responseJSON(w http.ResponseWriter, iData interface{}) {
@@ -68,6 +157,6 @@ func responseJSON(w http.ResponseWriter, iData interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	_, err = w.Write(data)
 	if err != nil {
-		log.Print("response write error: ", err)
+		logger.Errorf("response write error: %v", err)
 	}
 }
@@ -1,2 +0,0 @@
-INSERT INTO managers (name, phone, password, is_admin)
-VALUES ('vasya', '+992000000001', '$2a$10$OaUtjCNv2DT5x/dXcV.P3eYkIPIRtBr/v8Nluwifz6brSkfyXOh6m', true);
\ No newline at end of file
@@ -1,9 +1,10 @@
 module github.com/bdaler/crud
 
-go 1.15
+go 1.16
 
 require (
 	github.com/gorilla/mux v1.8.0
+	github.com/jackc/pgconn v1.7.2
 	github.com/jackc/pgx/v4 v4.9.2
 	go.uber.org/dig v1.10.0
 	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
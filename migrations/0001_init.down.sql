@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS sales_positions;
+DROP TABLE IF EXISTS sales;
+DROP TABLE IF EXISTS products;
+DROP TABLE IF EXISTS managers_tokens;
+DROP TABLE IF EXISTS customers_tokens;
+DROP TABLE IF EXISTS managers;
+DROP TABLE IF EXISTS customers;
@@ -65,4 +65,4 @@ CREATE TABLE IF NOT EXISTS sales_positions
     price      INTEGER   NOT NULL CHECK (price >= 0),
     qty        INTEGER   NOT NULL DEFAULT 0 CHECK (qty >= 0),
     created    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-);
\ No newline at end of file
+);
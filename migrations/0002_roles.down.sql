@@ -0,0 +1,12 @@
+ALTER TABLE managers
+    ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT TRUE;
+
+UPDATE managers m
+SET is_admin = EXISTS(SELECT 1
+                      FROM managers_roles mr
+                               JOIN roles r ON r.id = mr.role_id
+                      WHERE mr.manager_id = m.id
+                        AND r.name = 'ADMIN');
+
+DROP TABLE IF EXISTS managers_roles;
+DROP TABLE IF EXISTS roles;
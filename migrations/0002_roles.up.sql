@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS roles
+(
+    id   BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+INSERT INTO roles (name)
+VALUES ('MANAGER'),
+       ('ADMIN')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS managers_roles
+(
+    manager_id BIGINT NOT NULL REFERENCES managers ON DELETE CASCADE,
+    role_id    BIGINT NOT NULL REFERENCES roles,
+    PRIMARY KEY (manager_id, role_id)
+);
+
+INSERT INTO managers_roles (manager_id, role_id)
+SELECT m.id, r.id
+FROM managers m,
+     roles r
+WHERE r.name = 'MANAGER'
+   OR (r.name = 'ADMIN' AND m.is_admin)
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE managers
+    DROP COLUMN IF EXISTS is_admin;
@@ -0,0 +1 @@
+-- seeded admin may already own tokens and sales, so it is kept
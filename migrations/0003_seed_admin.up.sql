@@ -0,0 +1,10 @@
+INSERT INTO managers (name, phone, password)
+VALUES ('vasya', '+992000000001', '$2a$10$OaUtjCNv2DT5x/dXcV.P3eYkIPIRtBr/v8Nluwifz6brSkfyXOh6m')
+ON CONFLICT (phone) DO NOTHING;
+
+INSERT INTO managers_roles (manager_id, role_id)
+SELECT m.id, r.id
+FROM managers m,
+     roles r
+WHERE m.phone = '+992000000001'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS returns_positions;
+DROP TABLE IF EXISTS returns;
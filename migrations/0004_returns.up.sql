@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS returns
+(
+    id         BIGSERIAL PRIMARY KEY,
+    sale_id    BIGINT    NOT NULL REFERENCES sales,
+    manager_id BIGINT    NOT NULL REFERENCES managers,
+    reason     TEXT      NOT NULL DEFAULT '',
+    created    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS returns_positions
+(
+    id               BIGSERIAL PRIMARY KEY,
+    return_id        BIGINT    NOT NULL REFERENCES returns,
+    sale_position_id BIGINT    NOT NULL REFERENCES sales_positions,
+    product_id       BIGINT    NOT NULL REFERENCES products,
+    price            INTEGER   NOT NULL CHECK (price >= 0),
+    qty              INTEGER   NOT NULL CHECK (qty > 0),
+    created          TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS returns_sale_id_idx ON returns (sale_id);
+CREATE INDEX IF NOT EXISTS returns_positions_sale_position_id_idx ON returns_positions (sale_position_id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS carts_items;
+
+-- fails while there are sales without manager, assign them first
+ALTER TABLE sales ALTER COLUMN manager_id SET NOT NULL;
@@ -0,0 +1,11 @@
+-- sales made by customers themselves have no manager until one is assigned
+ALTER TABLE sales ALTER COLUMN manager_id DROP NOT NULL;
+
+CREATE TABLE IF NOT EXISTS carts_items
+(
+    customer_id BIGINT    NOT NULL REFERENCES customers ON DELETE CASCADE,
+    product_id  BIGINT    NOT NULL REFERENCES products ON DELETE CASCADE,
+    qty         INTEGER   NOT NULL CHECK (qty > 0),
+    created     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (customer_id, product_id)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS reservations_positions;
+DROP TABLE IF EXISTS reservations;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS reservations
+(
+    id          BIGSERIAL PRIMARY KEY,
+    customer_id BIGINT    NOT NULL DEFAULT 0,
+    manager_id  BIGINT REFERENCES managers,
+    expire      TIMESTAMP NOT NULL,
+    created     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS reservations_positions
+(
+    id             BIGSERIAL PRIMARY KEY,
+    reservation_id BIGINT  NOT NULL REFERENCES reservations ON DELETE CASCADE,
+    product_id     BIGINT  NOT NULL REFERENCES products ON DELETE CASCADE,
+    qty            INTEGER NOT NULL CHECK (qty > 0)
+);
+
+CREATE INDEX IF NOT EXISTS reservations_expire_idx ON reservations (expire);
+CREATE INDEX IF NOT EXISTS reservations_positions_product_id_idx ON reservations_positions (product_id);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS stock_movements
+(
+    id          BIGSERIAL PRIMARY KEY,
+    product_id  BIGINT    NOT NULL REFERENCES products ON DELETE CASCADE,
+    kind        TEXT      NOT NULL CHECK (kind IN ('receipt', 'sale', 'return', 'adjustment', 'write_off')),
+    qty         INTEGER   NOT NULL CHECK (qty <> 0),
+    manager_id  BIGINT REFERENCES managers,
+    customer_id BIGINT,
+    sale_id     BIGINT REFERENCES sales,
+    return_id   BIGINT REFERENCES returns,
+    reason      TEXT      NOT NULL DEFAULT '',
+    created     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS stock_movements_product_id_idx ON stock_movements (product_id, id);
+
+-- stock which existed before the ledger, so that qty reconciles with movements
+INSERT INTO stock_movements(product_id, kind, qty, reason)
+SELECT id, 'adjustment', qty, 'opening balance'
+FROM products
+WHERE qty <> 0;
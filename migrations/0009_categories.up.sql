@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS categories
+(
+    id        BIGSERIAL PRIMARY KEY,
+    parent_id BIGINT REFERENCES categories,
+    name      TEXT      NOT NULL,
+    created   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS categories_parent_id_idx ON categories (parent_id);
+
+CREATE TABLE IF NOT EXISTS products_categories
+(
+    product_id  BIGINT NOT NULL REFERENCES products ON DELETE CASCADE,
+    category_id BIGINT NOT NULL REFERENCES categories ON DELETE CASCADE,
+    PRIMARY KEY (product_id, category_id)
+);
+
+CREATE INDEX IF NOT EXISTS products_categories_category_id_idx ON products_categories (category_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS products_name_trgm_idx;
+DROP INDEX IF EXISTS products_search_idx;
+ALTER TABLE products DROP COLUMN IF EXISTS search;
+
+-- pg_trgm is left installed, other databases objects may use it
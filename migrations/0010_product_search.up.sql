@@ -0,0 +1,8 @@
+-- pg_trgm gives typo tolerant matching, creating it needs superuser or trusted extensions (PostgreSQL 13+)
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- 'simple' configuration doesn't stem, names are not in a single language
+ALTER TABLE products ADD COLUMN IF NOT EXISTS search TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', name)) STORED;
+
+CREATE INDEX IF NOT EXISTS products_search_idx ON products USING GIN (search);
+CREATE INDEX IF NOT EXISTS products_name_trgm_idx ON products USING GIN (name gin_trgm_ops);
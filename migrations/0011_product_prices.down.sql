@@ -0,0 +1,2 @@
+DROP FUNCTION IF EXISTS product_price(BIGINT, TIMESTAMP);
+DROP TABLE IF EXISTS product_prices;
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS product_prices
+(
+    id             BIGSERIAL PRIMARY KEY,
+    product_id     BIGINT    NOT NULL REFERENCES products ON DELETE CASCADE,
+    price          INTEGER   NOT NULL CHECK (price >= 0),
+    effective_from TIMESTAMP NOT NULL,
+    effective_to   TIMESTAMP CHECK (effective_to > effective_from),
+    manager_id     BIGINT REFERENCES managers,
+    created        TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS product_prices_product_id_idx ON product_prices (product_id, effective_from);
+
+-- prices which existed before the history
+INSERT INTO product_prices(product_id, price, effective_from)
+SELECT id, price, created
+FROM products;
+
+-- price of the product at the moment, the latest started interval wins;
+-- products.price keeps the last regular price and is only a fallback
+CREATE OR REPLACE FUNCTION product_price(product BIGINT, at TIMESTAMP) RETURNS INTEGER AS
+$$
+SELECT COALESCE((SELECT pp.price
+                 FROM product_prices pp
+                 WHERE pp.product_id = product
+                   AND pp.effective_from <= at
+                   AND (pp.effective_to IS NULL OR pp.effective_to > at)
+                 ORDER BY pp.effective_from DESC, pp.id DESC
+                 LIMIT 1),
+                (SELECT p.price FROM products p WHERE p.id = product))
+$$ LANGUAGE SQL STABLE;
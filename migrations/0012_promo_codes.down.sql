@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS sales_promo_code_id_idx;
+ALTER TABLE sales_positions DROP COLUMN IF EXISTS discount;
+ALTER TABLE sales DROP COLUMN IF EXISTS promo_code_id;
+DROP TABLE IF EXISTS promo_codes;
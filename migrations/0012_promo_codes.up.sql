@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS promo_codes
+(
+    id                    BIGSERIAL PRIMARY KEY,
+    code                  TEXT      NOT NULL UNIQUE,
+    kind                  TEXT      NOT NULL CHECK (kind IN ('percent', 'fixed')),
+    value                 INTEGER   NOT NULL CHECK (value > 0),
+    product_id            BIGINT REFERENCES products ON DELETE CASCADE,
+    min_total             INTEGER   NOT NULL DEFAULT 0 CHECK (min_total >= 0),
+    valid_from            TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    valid_to              TIMESTAMP CHECK (valid_to > valid_from),
+    max_uses              INTEGER   NOT NULL DEFAULT 0 CHECK (max_uses >= 0),
+    max_uses_per_customer INTEGER   NOT NULL DEFAULT 0 CHECK (max_uses_per_customer >= 0),
+    active                BOOLEAN   NOT NULL DEFAULT TRUE,
+    created               TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (kind <> 'percent' OR value <= 100)
+);
+
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS promo_code_id BIGINT REFERENCES promo_codes;
+ALTER TABLE sales_positions ADD COLUMN IF NOT EXISTS discount INTEGER NOT NULL DEFAULT 0 CHECK (discount >= 0);
+
+CREATE INDEX IF NOT EXISTS sales_promo_code_id_idx ON sales (promo_code_id, customer_id);
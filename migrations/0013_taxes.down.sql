@@ -0,0 +1,6 @@
+ALTER TABLE sales DROP COLUMN IF EXISTS tax;
+ALTER TABLE sales_positions DROP COLUMN IF EXISTS tax;
+ALTER TABLE sales_positions DROP COLUMN IF EXISTS tax_inclusive;
+ALTER TABLE sales_positions DROP COLUMN IF EXISTS tax_rate;
+ALTER TABLE categories DROP COLUMN IF EXISTS tax_inclusive;
+ALTER TABLE categories DROP COLUMN IF EXISTS tax_rate;
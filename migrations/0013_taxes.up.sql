@@ -0,0 +1,9 @@
+-- rate in basis points, NULL means the rate and mode of the parent category apply
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS tax_rate INTEGER CHECK (tax_rate BETWEEN 0 AND 10000);
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS tax_inclusive BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE sales_positions ADD COLUMN IF NOT EXISTS tax_rate INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sales_positions ADD COLUMN IF NOT EXISTS tax_inclusive BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE sales_positions ADD COLUMN IF NOT EXISTS tax INTEGER NOT NULL DEFAULT 0 CHECK (tax >= 0);
+
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS tax INTEGER NOT NULL DEFAULT 0 CHECK (tax >= 0);
@@ -0,0 +1,36 @@
+DROP FUNCTION IF EXISTS product_price(BIGINT, TIMESTAMP);
+CREATE FUNCTION product_price(product BIGINT, at TIMESTAMP) RETURNS INTEGER AS
+$$
+SELECT COALESCE((SELECT pp.price
+                 FROM product_prices pp
+                 WHERE pp.product_id = product
+                   AND pp.effective_from <= at
+                   AND (pp.effective_to IS NULL OR pp.effective_to > at)
+                 ORDER BY pp.effective_from DESC, pp.id DESC
+                 LIMIT 1),
+                (SELECT p.price FROM products p WHERE p.id = product))::INTEGER
+$$ LANGUAGE SQL STABLE;
+
+-- amounts go back to whole somoni, fractions are lost; prices and fixed values must stay positive
+UPDATE promo_codes SET value = GREATEST(value / 100, 1) WHERE kind = 'fixed';
+UPDATE promo_codes SET min_total = min_total / 100;
+UPDATE returns_positions SET price = price / 100;
+UPDATE sales_positions SET price = price / 100, discount = discount / 100, tax = tax / 100;
+UPDATE sales SET tax = tax / 100;
+UPDATE product_prices SET price = price / 100;
+UPDATE products SET price = GREATEST(price / 100, 1);
+
+ALTER TABLE promo_codes ALTER COLUMN min_total TYPE INTEGER;
+ALTER TABLE promo_codes ALTER COLUMN value TYPE INTEGER;
+ALTER TABLE promo_codes DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE returns_positions ALTER COLUMN price TYPE INTEGER;
+ALTER TABLE sales_positions ALTER COLUMN tax TYPE INTEGER;
+ALTER TABLE sales_positions ALTER COLUMN discount TYPE INTEGER;
+ALTER TABLE sales_positions ALTER COLUMN price TYPE INTEGER;
+ALTER TABLE sales ALTER COLUMN tax TYPE INTEGER;
+ALTER TABLE sales DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE product_prices ALTER COLUMN price TYPE INTEGER;
+ALTER TABLE products ALTER COLUMN price TYPE INTEGER;
+ALTER TABLE products DROP COLUMN IF EXISTS currency;
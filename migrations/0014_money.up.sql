@@ -0,0 +1,41 @@
+-- amounts are minor units of the currency; existing data is in somoni
+ALTER TABLE products ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT 'TJS' CHECK (currency ~ '^[A-Z]{3}$');
+ALTER TABLE products ALTER COLUMN currency DROP DEFAULT;
+ALTER TABLE products ALTER COLUMN price TYPE BIGINT;
+ALTER TABLE product_prices ALTER COLUMN price TYPE BIGINT;
+
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT 'TJS' CHECK (currency ~ '^[A-Z]{3}$');
+ALTER TABLE sales ALTER COLUMN currency DROP DEFAULT;
+ALTER TABLE sales ALTER COLUMN tax TYPE BIGINT;
+ALTER TABLE sales_positions ALTER COLUMN price TYPE BIGINT;
+ALTER TABLE sales_positions ALTER COLUMN discount TYPE BIGINT;
+ALTER TABLE sales_positions ALTER COLUMN tax TYPE BIGINT;
+ALTER TABLE returns_positions ALTER COLUMN price TYPE BIGINT;
+
+-- fixed value and min_total are in this currency
+ALTER TABLE promo_codes ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT 'TJS' CHECK (currency ~ '^[A-Z]{3}$');
+ALTER TABLE promo_codes ALTER COLUMN currency DROP DEFAULT;
+ALTER TABLE promo_codes ALTER COLUMN value TYPE BIGINT;
+ALTER TABLE promo_codes ALTER COLUMN min_total TYPE BIGINT;
+
+-- existing amounts are whole somoni, stored amounts are dirams from now on
+UPDATE products SET price = price * 100;
+UPDATE product_prices SET price = price * 100;
+UPDATE sales SET tax = tax * 100;
+UPDATE sales_positions SET price = price * 100, discount = discount * 100, tax = tax * 100;
+UPDATE returns_positions SET price = price * 100;
+UPDATE promo_codes SET min_total = min_total * 100;
+UPDATE promo_codes SET value = value * 100 WHERE kind = 'fixed';
+
+DROP FUNCTION IF EXISTS product_price(BIGINT, TIMESTAMP);
+CREATE FUNCTION product_price(product BIGINT, at TIMESTAMP) RETURNS BIGINT AS
+$$
+SELECT COALESCE((SELECT pp.price
+                 FROM product_prices pp
+                 WHERE pp.product_id = product
+                   AND pp.effective_from <= at
+                   AND (pp.effective_to IS NULL OR pp.effective_to > at)
+                 ORDER BY pp.effective_from DESC, pp.id DESC
+                 LIMIT 1),
+                (SELECT p.price FROM products p WHERE p.id = product))
+$$ LANGUAGE SQL STABLE;
@@ -0,0 +1,7 @@
+UPDATE stock_movements SET kind = 'adjustment', reason = 'sale voided' WHERE kind = 'void';
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_kind_check;
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_kind_check
+    CHECK (kind IN ('receipt', 'sale', 'return', 'adjustment', 'write_off'));
+
+DROP TABLE IF EXISTS payments;
+ALTER TABLE sales DROP COLUMN IF EXISTS voided;
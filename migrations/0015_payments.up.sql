@@ -0,0 +1,30 @@
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS voided TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS payments
+(
+    id         BIGSERIAL PRIMARY KEY,
+    sale_id    BIGINT    NOT NULL REFERENCES sales,
+    tender     TEXT      NOT NULL CHECK (tender IN ('cash', 'card', 'transfer', 'store_credit')),
+    amount     BIGINT    NOT NULL CHECK (amount >= 0),
+    tendered   BIGINT    NOT NULL CHECK (tendered >= amount),
+    change     BIGINT    NOT NULL DEFAULT 0 CHECK (change = tendered - amount),
+    reference  TEXT      NOT NULL DEFAULT '',
+    manager_id BIGINT REFERENCES managers,
+    created    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS payments_sale_id_idx ON payments (sale_id);
+
+-- sales made before payments were recorded were paid in cash
+INSERT INTO payments(sale_id, tender, amount, tendered, manager_id, created)
+SELECT s.id, 'cash', t.total, t.total, s.manager_id, s.created
+FROM sales s
+JOIN (SELECT sp.sale_id,
+             SUM(sp.qty * sp.price - sp.discount + CASE WHEN sp.tax_inclusive THEN 0 ELSE sp.tax END)::BIGINT AS total
+      FROM sales_positions sp
+      GROUP BY sp.sale_id) t ON t.sale_id = s.id
+WHERE t.total > 0;
+
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_kind_check;
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_kind_check
+    CHECK (kind IN ('receipt', 'sale', 'return', 'adjustment', 'write_off', 'void'));
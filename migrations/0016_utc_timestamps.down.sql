@@ -0,0 +1,19 @@
+ALTER TABLE payments ALTER COLUMN created SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE promo_codes ALTER COLUMN created SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE promo_codes ALTER COLUMN valid_from SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE product_prices ALTER COLUMN created SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE categories ALTER COLUMN created SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE stock_movements ALTER COLUMN created SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE reservations ALTER COLUMN created SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE carts_items ALTER COLUMN created SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE returns_positions ALTER COLUMN created SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE returns ALTER COLUMN created SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE sales_positions ALTER COLUMN created SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE sales ALTER COLUMN created SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE products ALTER COLUMN created SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE managers_tokens ALTER COLUMN created SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE managers_tokens ALTER COLUMN expire SET DEFAULT CURRENT_TIMESTAMP + INTERVAL '1 hour';
+ALTER TABLE customers_tokens ALTER COLUMN created SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE customers_tokens ALTER COLUMN expire SET DEFAULT CURRENT_TIMESTAMP + INTERVAL '1 hour';
+ALTER TABLE managers ALTER COLUMN created SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE customers ALTER COLUMN created SET DEFAULT CURRENT_TIMESTAMP;
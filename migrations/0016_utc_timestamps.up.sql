@@ -0,0 +1,21 @@
+-- TIMESTAMP columns hold UTC time, defaults follow the server time zone otherwise.
+-- Rows written before keep their values, the docker image runs in UTC.
+ALTER TABLE customers ALTER COLUMN created SET DEFAULT (now() AT TIME ZONE 'UTC');
+ALTER TABLE managers ALTER COLUMN created SET DEFAULT (now() AT TIME ZONE 'UTC');
+ALTER TABLE customers_tokens ALTER COLUMN expire SET DEFAULT (now() AT TIME ZONE 'UTC') + INTERVAL '1 hour';
+ALTER TABLE customers_tokens ALTER COLUMN created SET DEFAULT (now() AT TIME ZONE 'UTC');
+ALTER TABLE managers_tokens ALTER COLUMN expire SET DEFAULT (now() AT TIME ZONE 'UTC') + INTERVAL '1 hour';
+ALTER TABLE managers_tokens ALTER COLUMN created SET DEFAULT (now() AT TIME ZONE 'UTC');
+ALTER TABLE products ALTER COLUMN created SET DEFAULT (now() AT TIME ZONE 'UTC');
+ALTER TABLE sales ALTER COLUMN created SET DEFAULT (now() AT TIME ZONE 'UTC');
+ALTER TABLE sales_positions ALTER COLUMN created SET DEFAULT (now() AT TIME ZONE 'UTC');
+ALTER TABLE returns ALTER COLUMN created SET DEFAULT (now() AT TIME ZONE 'UTC');
+ALTER TABLE returns_positions ALTER COLUMN created SET DEFAULT (now() AT TIME ZONE 'UTC');
+ALTER TABLE carts_items ALTER COLUMN created SET DEFAULT (now() AT TIME ZONE 'UTC');
+ALTER TABLE reservations ALTER COLUMN created SET DEFAULT (now() AT TIME ZONE 'UTC');
+ALTER TABLE stock_movements ALTER COLUMN created SET DEFAULT (now() AT TIME ZONE 'UTC');
+ALTER TABLE categories ALTER COLUMN created SET DEFAULT (now() AT TIME ZONE 'UTC');
+ALTER TABLE product_prices ALTER COLUMN created SET DEFAULT (now() AT TIME ZONE 'UTC');
+ALTER TABLE promo_codes ALTER COLUMN valid_from SET DEFAULT (now() AT TIME ZONE 'UTC');
+ALTER TABLE promo_codes ALTER COLUMN created SET DEFAULT (now() AT TIME ZONE 'UTC');
+ALTER TABLE payments ALTER COLUMN created SET DEFAULT (now() AT TIME ZONE 'UTC');
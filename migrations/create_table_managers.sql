@@ -1,28 +0,0 @@
-CREATE TABLE IF NOT EXISTS managers (
-    id BIGSERIAL PRIMARY KEY,
-    name TEXT NOT NULL,
-    login TEXT NOT NULL UNIQUE,
-    password TEXT NOT NULL,
-    salary INTEGER NOT NULL CHECK(salary > 0),
-    plan INTEGER NOT NULL CHECK(plan > 0),
-    boss_id BIGINT NOT NULL,
-    department TEXT,
-    active BOOLEAN NOT NULL DEFAULT TRUE,
-    created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-)
-CREATE TABLE IF NOT EXISTS customers (
-    id BIGSERIAL PRIMARY KEY,
-    name TEXT NOT NULL,
-    phone TEXT NOT NULL UNIQUE,
-    password TEXT NOT NULL,
-    active BOOLEAN NOT NULL DEFAULT TRUE,
-    created TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
-CREATE TABLE IF NOT EXISTS customers_tokens (
-    token TEXT NOT NULL UNIQUE,
-    customer_id BIGINT NOT NULL REFERENCES customers,
-    expire TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP + INTERVAL '1 hour',
-    created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-);
-
@@ -0,0 +1,77 @@
+// Package alerts delivers low-stock notifications to a webhook or to the log.
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/bdaler/crud/pkg/config"
+	"github.com/bdaler/crud/pkg/logger"
+	"net/http"
+	"time"
+)
+
+// LowStock is sent when a sale drops product qty to or below its reorder level.
+type LowStock struct {
+	ProductID    int64     `json:"product_id"`
+	Name         string    `json:"name"`
+	Qty          int       `json:"qty"`
+	ReorderLevel int       `json:"reorder_level"`
+	SaleID       int64     `json:"sale_id"`
+	Created      time.Time `json:"created"`
+}
+
+type Notifier interface {
+	NotifyLowStock(ctx context.Context, alert *LowStock) error
+}
+
+// NewNotifier returns webhook notifier when alerts.webhook_url is configured,
+// otherwise alerts are only logged.
+func NewNotifier(cfg *config.Config) Notifier {
+	if cfg.Alerts.WebhookURL == "" {
+		return &LogNotifier{}
+	}
+	return &WebhookNotifier{
+		url:    cfg.Alerts.WebhookURL,
+		client: &http.Client{Timeout: time.Duration(cfg.Alerts.Timeout)},
+	}
+}
+
+type LogNotifier struct{}
+
+func (n *LogNotifier) NotifyLowStock(ctx context.Context, alert *LowStock) error {
+	logger.Infof("low stock: product %d %q qty %d reorder level %d after sale %d",
+		alert.ProductID, alert.Name, alert.Qty, alert.ReorderLevel, alert.SaleID)
+	return nil
+}
+
+// WebhookNotifier posts alert as JSON, any non-2xx response is an error.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n *WebhookNotifier) NotifyLowStock(ctx context.Context, alert *LowStock) error {
+	body, err := json.Marshal(map[string]interface{}{"type": "low_stock", "alert": alert})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s responded %s", n.url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setenv sets key for the test only, t.Setenv needs go 1.17.
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+	prev, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_defaults(t *testing.T) {
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("Load() = %+v, want defaults %+v", cfg, Default())
+	}
+	if cfg.Listen != "0.0.0.0:9999" || cfg.Storage != StoragePostgres || cfg.Currency != "TJS" {
+		t.Errorf("Load() listen, storage, currency = %q, %q, %q", cfg.Listen, cfg.Storage, cfg.Currency)
+	}
+	if cfg.Tokens.ManagerTTL != Duration(time.Hour) || !cfg.Tokens.Sliding {
+		t.Errorf("Load() tokens = %+v, want 1h sliding", cfg.Tokens)
+	}
+}
+
+func TestLoad_fileAndEnv(t *testing.T) {
+	path := writeConfig(t, `{
+		"listen": "127.0.0.1:8080",
+		"storage": "memory",
+		"currency": "USD",
+		"tokens": {"customer_ttl": "30m", "manager_ttl": "2h"},
+		"receipt": {"store_name": "Corner shop"}
+	}`)
+	setenv(t, "APP_LISTEN", "127.0.0.1:9090")
+	setenv(t, "APP_MANAGER_TOKEN_TTL", "1h30m")
+	setenv(t, "APP_CORS_ORIGINS", "http://a.tj, ,http://b.tj")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Listen != "127.0.0.1:9090" {
+		t.Errorf("listen = %q, want env value", cfg.Listen)
+	}
+	if cfg.Tokens.ManagerTTL != Duration(90*time.Minute) {
+		t.Errorf("manager_ttl = %v, want env value 1h30m", time.Duration(cfg.Tokens.ManagerTTL))
+	}
+	if cfg.Tokens.CustomerTTL != Duration(30*time.Minute) {
+		t.Errorf("customer_ttl = %v, want file value 30m", time.Duration(cfg.Tokens.CustomerTTL))
+	}
+	if cfg.Storage != StorageMemory || cfg.Currency != "USD" || cfg.Receipt.StoreName != "Corner shop" {
+		t.Errorf("storage, currency, store_name = %q, %q, %q, want file values", cfg.Storage, cfg.Currency, cfg.Receipt.StoreName)
+	}
+	if cfg.Receipt.Width != 42 || cfg.Server.ReadTimeout != Duration(15*time.Second) {
+		t.Errorf("receipt.width, read_timeout = %d, %v, want defaults", cfg.Receipt.Width, time.Duration(cfg.Server.ReadTimeout))
+	}
+	if !reflect.DeepEqual(cfg.CORSOrigins, []string{"http://a.tj", "http://b.tj"}) {
+		t.Errorf("cors_origins = %q", cfg.CORSOrigins)
+	}
+}
+
+func TestLoad_invalidDuration(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		path := writeConfig(t, `{"server": {"read_timeout": "soon"}}`)
+		if _, err := Load(path); err == nil || !strings.Contains(err.Error(), path) {
+			t.Errorf("Load() error = %v, want error about %s", err, path)
+		}
+	})
+	t.Run("file number", func(t *testing.T) {
+		path := writeConfig(t, `{"server": {"read_timeout": 15}}`)
+		if _, err := Load(path); err == nil {
+			t.Error("Load() accepted duration without unit")
+		}
+	})
+	t.Run("env", func(t *testing.T) {
+		setenv(t, "APP_RESERVATION_TTL", "15")
+		if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "APP_RESERVATION_TTL") {
+			t.Errorf("Load() error = %v, want error about APP_RESERVATION_TTL", err)
+		}
+	})
+	t.Run("not positive", func(t *testing.T) {
+		setenv(t, "APP_SHUTDOWN_TIMEOUT", "0s")
+		if _, err := Load(""); err == nil {
+			t.Error("Load() accepted zero shutdown timeout")
+		}
+	})
+}
+
+func TestLoad_missingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.json")
+	if _, err := Load(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Load() error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
@@ -2,281 +2,260 @@ package customers
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/hex"
-	"errors"
-	"github.com/jackc/pgx/v4"
-	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/bdaler/crud/pkg/config"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/bdaler/crud/pkg/managers"
+	"github.com/bdaler/crud/pkg/repository"
+	"github.com/bdaler/crud/pkg/security"
+	"github.com/bdaler/crud/pkg/types"
+	"github.com/bdaler/crud/pkg/utils"
 	"golang.org/x/crypto/bcrypt"
-	"log"
 	"time"
 )
 
-var ErrNotFound = errors.New("customer not found")
-var ErrInternal = errors.New("internal error")
-var ErrNoSuchUser = errors.New("no such user")
-var ErrInvalidPassword = errors.New("invalid password")
-
 type Service struct {
-	pool *pgxpool.Pool
+	customers repository.CustomerRepository
+	products  repository.ProductRepository
+	sales     repository.SaleRepository
+	carts     repository.CartRepository
+	cats      repository.CategoryRepository
+	sellers   *managers.Service
+	tokens    *security.Tokens
+	cost      int
 }
 
-func NewService(pool *pgxpool.Pool) *Service {
-	return &Service{pool: pool}
+func NewService(
+	customers repository.CustomerRepository,
+	products repository.ProductRepository,
+	sales repository.SaleRepository,
+	carts repository.CartRepository,
+	cats repository.CategoryRepository,
+	sellers *managers.Service,
+	tokens repository.TokenRepository,
+	cfg *config.Config,
+) *Service {
+	tokenOpts := &security.TokenOptions{
+		TTL:     time.Duration(cfg.Tokens.CustomerTTL),
+		Sliding: cfg.Tokens.Sliding,
+	}
+
+	return &Service{
+		customers: customers,
+		products:  products,
+		sales:     sales,
+		carts:     carts,
+		cats:      cats,
+		sellers:   sellers,
+		tokens:    security.NewTokens(tokens, repository.Customers, tokenOpts),
+		cost:      cfg.BcryptCost,
+	}
 }
 
-type Customer struct {
-	ID       int64     `json:"id"`
-	Name     string    `json:"name"`
-	Phone    string    `json:"phone"`
-	Password string    `json:"password"`
-	Active   bool      `json:"active"`
-	Created  time.Time `json:"created"`
+func (s *Service) ByID(ctx context.Context, id int64) (*types.Customer, error) {
+	return s.customers.ByID(ctx, id)
 }
 
-type Product struct {
-	ID    int64  `json:"id"`
-	Name  string `json:"name"`
-	Price int    `json:"price"`
-	Qty   int    `json:"qty"`
+func (s *Service) All(ctx context.Context) ([]*types.Customer, error) {
+	return s.customers.All(ctx)
 }
 
-func (s *Service) ByID(ctx context.Context, id int64) (*Customer, error) {
-	item := &Customer{}
+func (s *Service) AllActive(ctx context.Context) ([]*types.Customer, error) {
+	return s.customers.AllActive(ctx)
+}
 
-	sqlStatement := `SELECT * FROM customers WHERE id=$1`
-	err := s.pool.QueryRow(ctx, sqlStatement, id).Scan(
-		&item.ID,
-		&item.Name,
-		&item.Phone,
-		&item.Active,
-		&item.Created)
+func (s *Service) ChangeActive(ctx context.Context, id int64, active bool) (*types.Customer, error) {
+	return s.customers.ChangeActive(ctx, id, active)
+}
 
-	if err == pgx.ErrNoRows {
-		return nil, ErrNotFound
-	}
+func (s *Service) Delete(ctx context.Context, id int64) (*types.Customer, error) {
+	return s.customers.Delete(ctx, id)
+}
 
-	if err != nil {
-		log.Println(err)
-		return nil, ErrInternal
-	}
-	return item, nil
+// Save expects customer.Password to be already hashed.
+func (s *Service) Save(ctx context.Context, customer *types.Customer) (*types.Customer, error) {
+	return s.customers.Save(ctx, customer)
 }
 
-func (s *Service) All(ctx context.Context) (customers []*Customer, err error) {
-	sqlStatement := `SELECT * FROM customers`
-	rows, err := s.pool.Query(ctx, sqlStatement)
+func (s *Service) IDByToken(ctx context.Context, token string) (int64, error) {
+	return s.tokens.IDByToken(ctx, token)
+}
 
+func (s *Service) Token(ctx context.Context, phone, password string) (string, error) {
+	id, hash, err := s.customers.Credentials(ctx, phone)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		item := &Customer{}
-		err := rows.Scan(
-			&item.ID,
-			&item.Name,
-			&item.Phone,
-			&item.Active,
-			&item.Created,
-		)
 
-		if err != nil {
-			log.Println(err)
-		}
-
-		customers = append(customers, item)
+	err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err != nil {
+		return "", types.ErrInvalidPassword
 	}
 
-	return customers, nil
+	return s.tokens.Issue(ctx, id)
 }
 
-func (s *Service) AllActive(ctx context.Context) (customers []*Customer, err error) {
-	sqlStatement := `SELECT * FROM customers WHERE active = TRUE`
-	rows, err := s.pool.Query(ctx, sqlStatement)
+// Products lists only active products whatever filter says.
+func (s *Service) Products(ctx context.Context, filter *types.ProductFilter) (*types.ProductPage, error) {
+	filter.WithInactive = false
+	return s.products.List(ctx, filter)
+}
 
+// ChangeProfile updates name of the customer and, when newPassword is given,
+// replaces password after checking the old one.
+func (s *Service) ChangeProfile(ctx context.Context, id int64, name, oldPassword, newPassword string) (*types.Customer, error) {
+	customer, err := s.customers.ByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		item := &Customer{}
-		err := rows.Scan(
-			&item.ID,
-			&item.Name,
-			&item.Phone,
-			&item.Active,
-			&item.Created,
-		)
+
+	_, hash, err := s.customers.Credentials(ctx, customer.Phone)
+	if err != nil {
+		return nil, err
+	}
+
+	if newPassword != "" {
+		err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(oldPassword))
+		if err != nil {
+			validation := &types.ValidationError{}
+			validation.Add("old_password", "invalid")
+			return nil, validation
+		}
+
+		hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), s.cost)
 		if err != nil {
-			log.Println(err)
+			return nil, err
 		}
-		customers = append(customers, item)
+		hash = string(hashed)
 	}
 
-	return customers, nil
+	customer.Name = name
+	customer.Password = hash
+	return s.customers.Save(ctx, customer)
 }
 
-func (s *Service) ChangeActive(ctx context.Context, id int64, active bool) (*Customer, error) {
-	item := &Customer{}
-
-	sqlStatement := `UPDATE customers SET active=$2 WHERE id=$1 RETURNING *`
-	err := s.pool.QueryRow(ctx, sqlStatement, id, active).Scan(
-		&item.ID,
-		&item.Name,
-		&item.Phone,
-		&item.Active,
-		&item.Created)
+// Purchases lists sales of the customer whatever customer filter says.
+func (s *Service) Purchases(ctx context.Context, id int64, filter *types.SaleFilter) (*types.SalePage, error) {
+	filter.CustomerID = id
+	filter.ManagerID = 0
+	return s.sales.List(ctx, filter)
+}
 
-	if err == pgx.ErrNoRows {
-		return nil, ErrNotFound
+func (s *Service) SearchProducts(ctx context.Context, search *types.ProductSearch) ([]*types.ProductHit, error) {
+	if len(utils.SearchWords(search.Query)) == 0 {
+		validation := &types.ValidationError{}
+		validation.Add("q", "must contain letters or digits")
+		return nil, validation
 	}
+	return s.products.Search(ctx, search)
+}
 
+// Categories returns tree of all categories.
+func (s *Service) Categories(ctx context.Context) ([]*types.Category, error) {
+	items, err := s.cats.All(ctx)
 	if err != nil {
-		log.Print(err)
-		return nil, ErrInternal
+		return nil, err
 	}
-
-	return item, nil
+	return types.CategoryTree(items), nil
 }
 
-func (s *Service) Delete(ctx context.Context, id int64) (*Customer, error) {
-	item := &Customer{}
-
-	sqlStatement := `DELETE FROM customers WHERE id=$1 RETURNING *`
-	err := s.pool.QueryRow(ctx, sqlStatement, id).Scan(
-		&item.ID,
-		&item.Name,
-		&item.Phone,
-		&item.Active,
-		&item.Created)
+func (s *Service) Cart(ctx context.Context, id int64) (*types.Cart, error) {
+	return s.carts.ByCustomer(ctx, id)
+}
 
-	if err == pgx.ErrNoRows {
-		return nil, ErrNotFound
+// SetCartItem puts product to the cart or changes its qty, only active
+// products with enough stock are accepted.
+func (s *Service) SetCartItem(ctx context.Context, id, productID int64, qty int) (*types.Cart, error) {
+	if qty <= 0 {
+		return nil, types.ErrInvalidPosition
 	}
 
+	product, err := s.products.ByID(ctx, productID)
 	if err != nil {
-		log.Print(err)
-		return nil, ErrInternal
+		return nil, err
 	}
-
-	return item, nil
-}
-
-func (s *Service) Save(ctx context.Context, customer *Customer) (c *Customer, err error) {
-	item := &Customer{}
-
-	if customer.ID == 0 {
-		sqlStatement := `INSERT INTO customers(name, phone, password) VALUES ($1, $2, $3) RETURNING *`
-		err = s.pool.QueryRow(
-			ctx,
-			sqlStatement,
-			customer.Name,
-			customer.Phone,
-			customer.Password).Scan(
-			&item.ID,
-			&item.Name,
-			&item.Phone,
-			&item.Password,
-			&item.Active,
-			&item.Created)
-	} else {
-		sqlStatement := `UPDATE customers SET name=$1, phone=$2, password=$3 WHERE id=$4 RETURNING *`
-		err = s.pool.QueryRow(
-			ctx,
-			sqlStatement,
-			customer.Name,
-			customer.Phone,
-			customer.Password,
-			customer.ID).Scan(
-			&item.ID,
-			&item.Name,
-			&item.Phone,
-			&item.Password,
-			&item.Active,
-			&item.Created)
+	if !product.Active {
+		return nil, types.ErrInvalidPosition
 	}
-
-	if err != nil {
-		log.Print(err)
-		return nil, ErrInternal
+	if product.Available < qty {
+		return nil, types.ErrNotEnoughQty
 	}
 
-	return item, nil
-}
-func (s *Service) IDByToken(ctx context.Context, token string) (int64, error) {
-	var id int64
-	sqlStatement := `SELECT customer_id FROM customers_tokens WHERE token = $1`
-	err := s.pool.QueryRow(ctx, sqlStatement, token).Scan(&id)
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			return 0, nil
-		}
-		return 0, ErrInternal
+	if err = s.carts.SetItem(ctx, id, productID, qty); err != nil {
+		return nil, err
 	}
-	return id, nil
+	return s.carts.ByCustomer(ctx, id)
 }
 
-func (s *Service) Token(ctx context.Context, phone, password string) (string, error) {
-	var hash string
-	var id int64
-	err := s.pool.QueryRow(ctx,
-		"SELECT id, password FROM customers WHERE phone = $1",
-		phone).Scan(&id, &hash)
-	if err == pgx.ErrNoRows {
-		return "", ErrNoSuchUser
+func (s *Service) RemoveCartItem(ctx context.Context, id, productID int64) (*types.Cart, error) {
+	if err := s.carts.RemoveItem(ctx, id, productID); err != nil {
+		return nil, err
 	}
+	return s.carts.ByCustomer(ctx, id)
+}
+
+func (s *Service) ClearCart(ctx context.Context, id int64) error {
+	return s.carts.Clear(ctx, id)
+}
+
+// Checkout turns the cart into a sale without manager at current prices
+// and empties the cart.
+func (s *Service) Checkout(ctx context.Context, id int64, promoCode string) (*types.Sale, error) {
+	cart, err := s.carts.ByCustomer(ctx, id)
 	if err != nil {
-		return "", ErrInternal
+		return nil, err
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	if err != nil {
-		return "", ErrInvalidPassword
+	if len(cart.Items) == 0 {
+		return nil, types.ErrCartEmpty
 	}
 
-	buffer := make([]byte, 256)
-	n, err := rand.Read(buffer)
-	if n != len(buffer) || err != nil {
-		return "", ErrInternal
+	sale := &types.Sale{CustomerID: id, PromoCode: promoCode, Positions: make([]*types.SalePosition, 0, len(cart.Items))}
+	for _, item := range cart.Items {
+		sale.Positions = append(sale.Positions, &types.SalePosition{
+			ProductID: item.ProductID,
+			Price:     item.Price,
+			Qty:       item.Qty,
+		})
 	}
 
-	token := hex.EncodeToString(buffer)
-	_, err = s.pool.Exec(
-		ctx,
-		"INSERT INTO customers_tokens(token, customer_id) VALUES ($1, $2)",
-		token,
-		id)
+	sale, err = s.sellers.MakeSale(ctx, sale)
 	if err != nil {
-		return "", ErrInternal
+		return nil, err
+	}
+
+	// the sale is already made, stale cart is not a reason to fail
+	if err = s.carts.Clear(ctx, id); err != nil {
+		logger.Errorf("clear cart after checkout: %v", err)
 	}
 
-	return token, nil
+	return sale, nil
 }
 
-func (s *Service) Products(ctx context.Context) ([]*Product, error) {
-	items := make([]*Product, 0)
-	sqlStatement := `SELECT id, name, price, qty FROM products WHERE active = TRUE ORDER BY ID LIMIT 500`
-	rows, err := s.pool.Query(ctx, sqlStatement)
+func (s *Service) Reserve(ctx context.Context, id int64, positions []*types.ReservationPosition) (*types.Reservation, error) {
+	return s.sellers.Reserve(ctx, &types.Reservation{CustomerID: id, Positions: positions})
+}
+
+// Reservation returns types.ErrNotFound for reservations of other customers.
+func (s *Service) Reservation(ctx context.Context, id, reservationID int64) (*types.Reservation, error) {
+	reservation, err := s.sellers.Reservation(ctx, reservationID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return items, nil
-		}
-		return nil, ErrInternal
+		return nil, err
 	}
+	if reservation.CustomerID != id {
+		return nil, types.ErrNotFound
+	}
+	return reservation, nil
+}
 
-	defer rows.Close()
-
-	for rows.Next() {
-		item := &Product{}
-		err = rows.Scan(&item.ID, &item.Name, &item.Price, &item.Qty)
-		if err != nil {
-			log.Print(err)
-			return nil, err
-		}
-		items = append(items, item)
+func (s *Service) ReleaseReservation(ctx context.Context, id, reservationID int64) error {
+	if _, err := s.Reservation(ctx, id, reservationID); err != nil {
+		return err
 	}
+	return s.sellers.ReleaseReservation(ctx, reservationID)
+}
 
-	return items, nil
+func (s *Service) ConfirmReservation(ctx context.Context, id, reservationID int64) (*types.Sale, error) {
+	if _, err := s.Reservation(ctx, id, reservationID); err != nil {
+		return nil, err
+	}
+	return s.sellers.ConfirmReservation(ctx, reservationID, 0)
 }
@@ -0,0 +1,118 @@
+package customers
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/alerts"
+	"github.com/bdaler/crud/pkg/config"
+	"github.com/bdaler/crud/pkg/managers"
+	"github.com/bdaler/crud/pkg/repository/memory"
+	"github.com/bdaler/crud/pkg/types"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+	"time"
+)
+
+func newTestService(cfg *config.Config) *Service {
+	db := memory.NewDB()
+	sellers := managers.NewService(
+		memory.NewManagerRepository(db),
+		memory.NewCustomerRepository(db),
+		memory.NewProductRepository(db),
+		memory.NewSaleRepository(db),
+		memory.NewReturnRepository(db),
+		memory.NewReservationRepository(db),
+		memory.NewStockRepository(db),
+		memory.NewCategoryRepository(db),
+		memory.NewPriceRepository(db),
+		memory.NewPromoRepository(db),
+		&alerts.LogNotifier{},
+		memory.NewTokenRepository(db),
+		cfg,
+	)
+	return NewService(
+		memory.NewCustomerRepository(db),
+		memory.NewProductRepository(db),
+		memory.NewSaleRepository(db),
+		memory.NewCartRepository(db),
+		memory.NewCategoryRepository(db),
+		sellers,
+		memory.NewTokenRepository(db),
+		cfg,
+	)
+}
+
+// register does what the registration handler does, passwords are hashed before Save.
+func register(t *testing.T, svc *Service, name, phone, password string) *types.Customer {
+	t.Helper()
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	customer, err := svc.Save(context.Background(), &types.Customer{Name: name, Phone: phone, Password: string(hashed)})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	return customer
+}
+
+func TestService_Register(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(config.Default())
+
+	customer := register(t, svc, "Ali", "+992111", "p1")
+	if customer.ID == 0 || !customer.Active {
+		t.Errorf("Save() = %+v, want active customer with id", customer)
+	}
+
+	_, err := svc.Save(ctx, &types.Customer{Name: "Other", Phone: "+992111", Password: "x"})
+	if err != types.ErrPhoneUsed {
+		t.Errorf("Save() same phone error = %v, want %v", err, types.ErrPhoneUsed)
+	}
+}
+
+func TestService_Token(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(config.Default())
+	customer := register(t, svc, "Ali", "+992111", "p1")
+
+	if _, err := svc.Token(ctx, "+992111", "wrong"); err != types.ErrInvalidPassword {
+		t.Errorf("Token() error = %v, want %v", err, types.ErrInvalidPassword)
+	}
+	if _, err := svc.Token(ctx, "+992222", "p1"); err == nil {
+		t.Error("Token() accepted unknown phone")
+	}
+
+	token, err := svc.Token(ctx, "+992111", "p1")
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	id, err := svc.IDByToken(ctx, token)
+	if err != nil || id != customer.ID {
+		t.Errorf("IDByToken() = %d, %v, want %d", id, err, customer.ID)
+	}
+}
+
+func TestService_TokenExpired(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Default()
+	cfg.Tokens.CustomerTTL = config.Duration(100 * time.Millisecond)
+	svc := newTestService(cfg)
+	register(t, svc, "Ali", "+992111", "p1")
+
+	token, err := svc.Token(ctx, "+992111", "p1")
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	// sliding lookups keep the token alive
+	for i := 0; i < 3; i++ {
+		time.Sleep(40 * time.Millisecond)
+		if _, err = svc.IDByToken(ctx, token); err != nil {
+			t.Fatalf("IDByToken() error = %v", err)
+		}
+	}
+	time.Sleep(150 * time.Millisecond)
+
+	if _, err = svc.IDByToken(ctx, token); err != types.ErrTokenExpired {
+		t.Errorf("IDByToken() error = %v, want %v", err, types.ErrTokenExpired)
+	}
+}
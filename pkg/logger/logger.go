@@ -0,0 +1,51 @@
+// Package logger adds levels on top of the standard log package.
+package logger
+
+import (
+	"log"
+	"sync/atomic"
+)
+
+const (
+	LevelDebug int32 = iota
+	LevelInfo
+	LevelError
+)
+
+var level = LevelInfo
+
+func SetLevel(name string) {
+	switch name {
+	case "debug":
+		atomic.StoreInt32(&level, LevelDebug)
+	case "error":
+		atomic.StoreInt32(&level, LevelError)
+	default:
+		atomic.StoreInt32(&level, LevelInfo)
+	}
+}
+
+func enabled(l int32) bool {
+	return atomic.LoadInt32(&level) <= l
+}
+
+func Debugf(format string, args ...interface{}) {
+	if enabled(LevelDebug) {
+		log.Printf("DEBUG "+format, args...)
+	}
+}
+
+func Infof(format string, args ...interface{}) {
+	if enabled(LevelInfo) {
+		log.Printf("INFO "+format, args...)
+	}
+}
+
+func Errorf(format string, args ...interface{}) {
+	log.Printf("ERROR "+format, args...)
+}
+
+// Error logs args the way log.Print does.
+func Error(args ...interface{}) {
+	log.Print(append([]interface{}{"ERROR "}, args...)...)
+}
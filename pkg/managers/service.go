@@ -2,84 +2,144 @@ package managers
 
 import (
 	"context"
+	"fmt"
+	"github.com/bdaler/crud/pkg/alerts"
+	"github.com/bdaler/crud/pkg/config"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/bdaler/crud/pkg/receipts"
+	"github.com/bdaler/crud/pkg/repository"
+	"github.com/bdaler/crud/pkg/security"
 	"github.com/bdaler/crud/pkg/types"
-	"github.com/bdaler/crud/pkg/utils"
-	"github.com/jackc/pgx/v4"
-	"github.com/jackc/pgx/v4/pgxpool"
 	"golang.org/x/crypto/bcrypt"
-	"log"
-	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 type Service struct {
-	pool *pgxpool.Pool
+	managers  repository.ManagerRepository
+	customers repository.CustomerRepository
+	products  repository.ProductRepository
+	sales     repository.SaleRepository
+	returns   repository.ReturnRepository
+	reserved  repository.ReservationRepository
+	stock     repository.StockRepository
+	cats      repository.CategoryRepository
+	prices    repository.PriceRepository
+	promos    repository.PromoRepository
+	notifier  alerts.Notifier
+	tokens    *security.Tokens
+	holdTTL   time.Duration
+	currency  string
+	receipt   config.Receipt
+
+	// background is cancelled by Shutdown, alerts are tracked by wg
+	background context.Context
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
 }
 
-func NewService(db *pgxpool.Pool) *Service {
-	return &Service{pool: db}
-}
-
-func (s *Service) IDByToken(ctx context.Context, token string) (int64, error) {
-	var id int64
-	sqlStatement := `SELECT manager_id FROM managers_tokens WHERE token = $1`
-	err := s.pool.QueryRow(ctx, sqlStatement, token).Scan(&id)
-	if err != nil {
-		log.Print(err)
-		if err == pgx.ErrNoRows {
-			return 0, nil
-		}
-		return 0, nil
+func NewService(
+	managers repository.ManagerRepository,
+	customers repository.CustomerRepository,
+	products repository.ProductRepository,
+	sales repository.SaleRepository,
+	returns repository.ReturnRepository,
+	reserved repository.ReservationRepository,
+	stock repository.StockRepository,
+	cats repository.CategoryRepository,
+	prices repository.PriceRepository,
+	promos repository.PromoRepository,
+	notifier alerts.Notifier,
+	tokens repository.TokenRepository,
+	cfg *config.Config,
+) *Service {
+	tokenOpts := &security.TokenOptions{
+		TTL:     time.Duration(cfg.Tokens.ManagerTTL),
+		Sliding: cfg.Tokens.Sliding,
 	}
 
-	return id, nil
+	background, cancel := context.WithCancel(context.Background())
+	return &Service{
+		managers:  managers,
+		customers: customers,
+		products:  products,
+		sales:     sales,
+		returns:   returns,
+		reserved:  reserved,
+		stock:     stock,
+		cats:      cats,
+		prices:    prices,
+		promos:    promos,
+		notifier:  notifier,
+		tokens:    security.NewTokens(tokens, repository.Managers, tokenOpts),
+		holdTTL:   time.Duration(cfg.Reservations.TTL),
+		currency:  cfg.Currency,
+		receipt:   cfg.Receipt,
+
+		background: background,
+		cancel:     cancel,
+	}
 }
 
-func (s *Service) IsAdmin(ctx context.Context, id int64) (isAdmin bool) {
-	sqlStmt := `SELECT is_admin FROM managers WHERE id = $1`
-	err := s.pool.QueryRow(ctx, sqlStmt, id).Scan(&isAdmin)
-	if err != nil {
-		return false
+// Shutdown waits for alerts in flight until ctx is done, then cancels them
+// and waits for them to return.
+func (s *Service) Shutdown(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
 	}
+	s.cancel()
+	<-done
+}
 
-	return
+func (s *Service) IDByToken(ctx context.Context, token string) (int64, error) {
+	return s.tokens.IDByToken(ctx, token)
+}
+
+func (s *Service) HasAnyRole(ctx context.Context, id int64, roles ...string) (bool, error) {
+	return s.managers.HasAnyRole(ctx, id, roles...)
 }
 
 func (s *Service) Create(ctx context.Context, item *types.Manager) (string, error) {
-	var token string
-	var id int64
-	sqlStmt := `INSERT INTO managers(name,phone,is_admin) VALUES ($1,$2,$3) ON CONFLICT (phone) DO NOTHING RETURNING id;`
-	err := s.pool.QueryRow(ctx, sqlStmt, item.Name, item.Phone, item.IsAdmin).Scan(&id)
-	if err != nil {
-		log.Print(err)
-		return "", types.ErrInternal
+	item.Roles = uniqueStrings(item.Roles)
+	if len(item.Roles) == 0 {
+		item.Roles = []string{types.RoleManager}
 	}
 
-	token, err = utils.GenerateTokenStr()
+	id, err := s.managers.Create(ctx, item)
 	if err != nil {
 		return "", err
 	}
 
-	_, err = s.pool.Exec(ctx, `INSERT INTO managers_tokens(token,manager_id) VALUES($1,$2)`, token, id)
-	if err != nil {
-		return "", types.ErrInternal
-	}
+	return s.tokens.Issue(ctx, id)
+}
 
-	return token, nil
+func uniqueStrings(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+	return result
 }
 
 func (s *Service) Token(ctx context.Context, phone, password string) (token string, err error) {
-	var hash string
-	var id int64
-	err = s.pool.QueryRow(
-		ctx,
-		`SELECT id, password FROM managers WHERE phone = $1`,
-		phone).Scan(&id, &hash)
-
-	if err == pgx.ErrNoRows {
+	id, hash, err := s.managers.Credentials(ctx, phone)
+	if err == types.ErrNoSuchUser {
 		return "", types.ErrInvalidPassword
 	}
 	if err != nil {
-		return "", types.ErrInternal
+		return "", err
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
@@ -87,219 +147,492 @@ func (s *Service) Token(ctx context.Context, phone, password string) (token stri
 		return "", types.ErrInvalidPassword
 	}
 
-	token, err = utils.GenerateTokenStr()
-	if err != nil {
-		return "", err
+	return s.tokens.Issue(ctx, id)
+}
+
+// SaveProduct records qty change as a movement made by managerID. New products
+// without currency are priced in the default one, currency of existing products
+// can't be changed.
+func (s *Service) SaveProduct(ctx context.Context, product *types.Product, managerID int64) (*types.Product, error) {
+	if product.ID == 0 && product.Price.Currency == "" {
+		product.Price.Currency = s.currency
 	}
 
-	_, err = s.pool.Exec(
-		ctx, `INSERT INTO managers_tokens(token,manager_id) VALUES ($1,$2)`,
-		token,
-		id)
-	if err != nil {
-		return "", types.ErrInternal
-	}
-
-	return token, nil
-}
-
-func (s *Service) SaveProduct(ctx context.Context, product *types.Product) (*types.Product, error) {
-	var err error
-
-	if product.ID == 0 {
-		sql := `INSERT INTO products(name,qty,price) VALUES ($1,$2,$3) RETURNING id, name, qty, price, active, created;`
-		err = s.pool.QueryRow(
-			ctx,
-			sql,
-			product.Name,
-			product.Qty,
-			product.Price).
-			Scan(&product.ID,
-				&product.Name,
-				&product.Qty,
-				&product.Price,
-				&product.Active,
-				&product.Created)
-	} else {
-		sql := `UPDATE products SET name=$1, qty=$2,price=$3 WHERE id = $4 RETURNING id, name, qty, price, active, created;`
-		err = s.pool.QueryRow(
-			ctx,
-			sql,
-			product.Name,
-			product.Qty,
-			product.Price,
-			product.ID).
-			Scan(&product.ID,
-				&product.Name,
-				&product.Qty,
-				&product.Price,
-				&product.Active,
-				&product.Created)
+	validation := &types.ValidationError{}
+	if strings.TrimSpace(product.Name) == "" {
+		validation.Add("name", "required")
+	}
+	if product.Price.Amount <= 0 {
+		validation.Add("price", "must be positive")
+	}
+	if product.Qty < 0 {
+		validation.Add("qty", "must not be negative")
+	}
+	if product.ReorderLevel < 0 {
+		validation.Add("reorder_level", "must not be negative")
+	}
+	if product.Price.Currency != "" && !types.ValidCurrency(product.Price.Currency) {
+		validation.Add("price.currency", "must be ISO 4217 code")
+	}
+	if err := validation.Err(); err != nil {
+		return nil, err
 	}
+	return s.products.Save(ctx, product, managerID)
+}
 
+// SchedulePrice adds price of the product for the future period, open-ended
+// when EffectiveTo is nil. Price is in currency of the product.
+func (s *Service) SchedulePrice(ctx context.Context, price *types.ProductPrice) (*types.ProductPrice, error) {
+	product, err := s.products.ByID(ctx, price.ProductID)
 	if err != nil {
-		log.Print(err)
-		return nil, types.ErrInternal
+		return nil, err
+	}
+	if price.Price.Currency == "" {
+		price.Price.Currency = product.Price.Currency
+	} else if price.Price.Currency != product.Price.Currency {
+		return nil, types.ErrCurrencyMismatch
+	}
+
+	validation := &types.ValidationError{}
+	if price.Price.Amount < 0 {
+		validation.Add("price", "must not be negative")
+	}
+	if price.EffectiveFrom.IsZero() {
+		validation.Add("effective_from", "required")
+	} else if !price.EffectiveFrom.After(time.Now()) {
+		validation.Add("effective_from", "must be in the future")
+	}
+	if price.EffectiveTo != nil && !price.EffectiveTo.After(price.EffectiveFrom) {
+		validation.Add("effective_to", "must be after effective_from")
+	}
+	if err := validation.Err(); err != nil {
+		return nil, err
+	}
+
+	price.ID = 0
+	price.EffectiveFrom = price.EffectiveFrom.UTC()
+	if price.EffectiveTo != nil {
+		end := price.EffectiveTo.UTC()
+		price.EffectiveTo = &end
 	}
-	return product, nil
+	return s.prices.Schedule(ctx, price)
 }
 
-func (s *Service) MakeSalePosition(ctx context.Context, position *types.SalePosition) bool {
-	active := false
-	qty := 0
-	if err := s.pool.QueryRow(
-		ctx, `SELECT qty,active FROM products WHERE id = $1`, position.ProductID).
-		Scan(&qty, &active); err != nil {
-		return false
+func (s *Service) ProductPrices(ctx context.Context, productID int64) ([]*types.ProductPrice, error) {
+	return s.prices.ByProduct(ctx, productID)
+}
+
+// PriceAt returns price of the product effective at given moment, zero means now.
+func (s *Service) PriceAt(ctx context.Context, productID int64, at time.Time) (*types.ProductPrice, error) {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return s.prices.At(ctx, productID, at.UTC())
+}
+
+func (s *Service) CancelPrice(ctx context.Context, productID, id int64) error {
+	return s.prices.Cancel(ctx, productID, id)
+}
+
+// MoveStock posts receipt, write-off or adjustment of the product. Qty of receipts
+// and write-offs is positive, write-offs decrease stock; adjustments are signed.
+func (s *Service) MoveStock(ctx context.Context, movement *types.StockMovement) (*types.StockMovement, error) {
+	validation := &types.ValidationError{}
+	switch movement.Kind {
+	case types.MovementReceipt, types.MovementWriteOff:
+		if movement.Qty <= 0 {
+			validation.Add("qty", "must be positive")
+		}
+	case types.MovementAdjustment:
+		if movement.Qty == 0 {
+			validation.Add("qty", "must not be zero")
+		}
+		if strings.TrimSpace(movement.Reason) == "" {
+			validation.Add("reason", "required")
+		}
+	default:
+		validation.Add("kind", "must be one of receipt, adjustment, write_off")
 	}
-	if qty < position.Qty || !active {
-		return false
+	if err := validation.Err(); err != nil {
+		return nil, err
 	}
-	if _, err := s.pool.Exec(
-		ctx, `UPDATE products SET qty = $1 WHERE id = $2`,
-		qty-position.Qty,
-		position.ProductID); err != nil {
-		log.Print(err)
-		return false
+
+	if movement.Kind == types.MovementWriteOff {
+		movement.Qty = -movement.Qty
 	}
+	movement.CustomerID = 0
+	movement.SaleID = 0
+	movement.ReturnID = 0
+	return s.stock.Move(ctx, movement)
+}
 
-	return true
+func (s *Service) Movements(ctx context.Context, filter *types.MovementFilter) (*types.MovementPage, error) {
+	if _, err := s.products.ByID(ctx, filter.ProductID); err != nil {
+		return nil, err
+	}
+	return s.stock.Movements(ctx, filter)
+}
+
+// StockMismatches lists products whose qty doesn't match their movements,
+// empty result means the ledger is consistent.
+func (s *Service) StockMismatches(ctx context.Context) ([]*types.StockMismatch, error) {
+	return s.stock.Mismatches(ctx)
 }
 
 func (s *Service) MakeSale(ctx context.Context, sale *types.Sale) (*types.Sale, error) {
-	positionsSql := "INSERT INTO sales_positions (sale_id,product_id,qty,price) VALUES "
-	sql := `INSERT INTO sales(manager_id,customer_id) VALUES ($1,$2) RETURNING id, created;`
-	err := s.pool.QueryRow(
-		ctx,
-		sql,
-		sale.ManagerID,
-		sale.CustomerID).Scan(&sale.ID, &sale.Created)
-	if err != nil {
-		log.Print(err)
-		return nil, types.ErrInternal
+	if len(sale.Positions) == 0 {
+		return nil, types.ErrInvalidPosition
 	}
 	for _, position := range sale.Positions {
-		if !s.MakeSalePosition(ctx, position) {
-			log.Print("Invalid position")
-			return nil, types.ErrInternal
+		// price is taken from the product at the time of sale
+		if position == nil || position.Qty <= 0 {
+			return nil, types.ErrInvalidPosition
 		}
-		positionsSql += "(" + strconv.FormatInt(sale.ID, 10) + "," + strconv.FormatInt(position.ProductID, 10) + "," + strconv.Itoa(position.Price) + "," + strconv.Itoa(position.Qty) + "),"
+		position.Discount = types.Money{}
+	}
+	sale.PromoCode = normalizePromoCode(sale.PromoCode)
+	sale.PromoCodeID = 0
+	sale.Voided = nil
+	if err := preparePayments(sale.Payments, sale.ManagerID); err != nil {
+		return nil, err
 	}
 
-	positionsSql = positionsSql[0 : len(positionsSql)-1]
-
-	log.Print(positionsSql)
-	_, err = s.pool.Exec(ctx, positionsSql)
+	sale, err := s.sales.Create(ctx, sale)
 	if err != nil {
-		log.Print(err)
-		return nil, types.ErrInternal
+		return nil, err
 	}
 
+	s.alertLowStock(sale)
 	return sale, nil
 }
 
-func (s *Service) GetSales(ctx context.Context, id int64) (sum int, err error) {
+// PaySale records payments made for the sale, several tenders can be paid at once.
+func (s *Service) PaySale(ctx context.Context, saleID, managerID int64, payments []*types.Payment) (*types.Sale, error) {
+	if len(payments) == 0 {
+		validation := &types.ValidationError{}
+		validation.Add("payments", "required")
+		return nil, validation
+	}
+	if err := preparePayments(payments, managerID); err != nil {
+		return nil, err
+	}
+	return s.sales.Pay(ctx, saleID, payments)
+}
+
+// VoidSale cancels the sale which has no payments, its goods go back in stock.
+func (s *Service) VoidSale(ctx context.Context, saleID, managerID int64) (*types.Sale, error) {
+	return s.sales.Void(ctx, saleID, managerID)
+}
+
+// preparePayments validates payments from the client and resets what's calculated.
+func preparePayments(payments []*types.Payment, managerID int64) error {
+	validation := &types.ValidationError{}
+	for i, payment := range payments {
+		field := fmt.Sprintf("payments[%d]", i)
+		if payment == nil {
+			validation.Add(field, "required")
+			continue
+		}
+		switch payment.Tender {
+		case types.TenderCash, types.TenderCard, types.TenderTransfer, types.TenderStoreCredit:
+		default:
+			validation.Add(field+".tender", "must be one of cash, card, transfer, store_credit")
+		}
+		if payment.Amount.Amount <= 0 {
+			validation.Add(field+".amount", "must be positive")
+		}
+		payment.ID = 0
+		payment.Tendered = types.Money{}
+		payment.Change = types.Money{}
+		payment.ManagerID = managerID
+		payment.Reference = strings.TrimSpace(payment.Reference)
+	}
+	return validation.Err()
+}
 
-	sqlstmt := `
-	SELECT COALESCE(SUM(sp.qty * sp.price),0) total
-	FROM managers m
-	LEFT JOIN sales s ON s.manager_id= $1
-	LEFT JOIN sales_positions sp ON sp.sale_id = s.id
-	GROUP BY m.id
-	LIMIT 1`
+// alertLowStock notifies about products which the sale dropped to or below
+// their reorder level, repositories find them when stock is changed.
+func (s *Service) alertLowStock(sale *types.Sale) {
+	for _, product := range sale.LowStock {
+		alert := &alerts.LowStock{
+			ProductID:    product.ID,
+			Name:         product.Name,
+			Qty:          product.Qty,
+			ReorderLevel: product.ReorderLevel,
+			SaleID:       sale.ID,
+			Created:      sale.Created,
+		}
+		// request may be finished before webhook responds
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			if err := s.notifier.NotifyLowStock(s.background, alert); err != nil {
+				logger.Errorf("low stock alert: %v", err)
+			}
+		}()
+	}
+}
 
-	err = s.pool.QueryRow(ctx, sqlstmt, id).Scan(&sum)
+// normalizePromoCode makes codes case-insensitive.
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// SavePromo creates or updates promo code, zero ValidFrom means now and empty
+// Currency means the default one.
+func (s *Service) SavePromo(ctx context.Context, promo *types.PromoCode) (*types.PromoCode, error) {
+	promo.Code = normalizePromoCode(promo.Code)
+	if promo.ValidFrom.IsZero() {
+		promo.ValidFrom = time.Now()
+	}
+	if promo.Currency == "" {
+		promo.Currency = s.currency
+	}
+
+	validation := &types.ValidationError{}
+	if promo.Code == "" {
+		validation.Add("code", "required")
+	}
+	switch promo.Kind {
+	case types.PromoPercent:
+		if promo.Value <= 0 || promo.Value > 100 {
+			validation.Add("value", "must be between 1 and 100")
+		}
+	case types.PromoFixed:
+		if promo.Value <= 0 {
+			validation.Add("value", "must be positive")
+		}
+	default:
+		validation.Add("kind", "must be one of percent, fixed")
+	}
+	if !types.ValidCurrency(promo.Currency) {
+		validation.Add("currency", "must be ISO 4217 code")
+	}
+	if promo.MinTotal < 0 {
+		validation.Add("min_total", "must not be negative")
+	}
+	if promo.MaxUses < 0 {
+		validation.Add("max_uses", "must not be negative")
+	}
+	if promo.MaxUsesPerCustomer < 0 {
+		validation.Add("max_uses_per_customer", "must not be negative")
+	}
+	if promo.ValidTo != nil && !promo.ValidTo.After(promo.ValidFrom) {
+		validation.Add("valid_to", "must be after valid_from")
+	}
+	if err := validation.Err(); err != nil {
+		return nil, err
+	}
+
+	promo.ValidFrom = promo.ValidFrom.UTC()
+	if promo.ValidTo != nil {
+		end := promo.ValidTo.UTC()
+		promo.ValidTo = &end
+	}
+	return s.promos.Save(ctx, promo)
+}
+
+func (s *Service) Promos(ctx context.Context) ([]*types.PromoCode, error) {
+	return s.promos.All(ctx)
+}
+
+func (s *Service) PromoByID(ctx context.Context, id int64) (*types.PromoCode, error) {
+	return s.promos.ByID(ctx, id)
+}
+
+// Categories returns tree of all categories.
+func (s *Service) Categories(ctx context.Context) ([]*types.Category, error) {
+	items, err := s.cats.All(ctx)
 	if err != nil {
-		log.Print(err)
-		return 0, types.ErrInternal
+		return nil, err
+	}
+	return types.CategoryTree(items), nil
+}
+
+func (s *Service) SaveCategory(ctx context.Context, category *types.Category) (*types.Category, error) {
+	validation := &types.ValidationError{}
+	if strings.TrimSpace(category.Name) == "" {
+		validation.Add("name", "required")
+	}
+	if category.TaxRate != nil && (*category.TaxRate < 0 || *category.TaxRate > types.MaxTaxRate) {
+		validation.Add("tax_rate", "must be between 0 and 10000 basis points")
+	}
+	if err := validation.Err(); err != nil {
+		return nil, err
+	}
+	if category.ID != 0 && category.ParentID == category.ID {
+		return nil, types.ErrCategoryCycle
+	}
+	return s.cats.Save(ctx, category)
+}
+
+func (s *Service) RemoveCategory(ctx context.Context, id int64) error {
+	return s.cats.Delete(ctx, id)
+}
+
+func (s *Service) ProductCategories(ctx context.Context, productID int64) ([]*types.Category, error) {
+	if _, err := s.products.ByID(ctx, productID); err != nil {
+		return nil, err
 	}
-	return sum, nil
+	return s.cats.ByProduct(ctx, productID)
+}
+
+// SetProductCategories replaces categories of the product, empty list removes it from all.
+func (s *Service) SetProductCategories(ctx context.Context, productID int64, categoryIDs []int64) ([]*types.Category, error) {
+	if _, err := s.products.ByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	if err := s.cats.SetProductCategories(ctx, productID, categoryIDs); err != nil {
+		return nil, err
+	}
+	return s.cats.ByProduct(ctx, productID)
+}
+
+func (s *Service) LowStockProducts(ctx context.Context) ([]*types.Product, error) {
+	return s.products.LowStock(ctx)
 }
 
-func (s *Service) Products(ctx context.Context) ([]*types.Product, error) {
-	items := make([]*types.Product, 0)
-	sql := `SELECT id, name, price, qty FROM products WHERE active = TRUE ORDER BY ID LIMIT 500`
-	rows, err := s.pool.Query(ctx, sql)
+// GetSales returns totals of the manager's sales, one per currency.
+func (s *Service) GetSales(ctx context.Context, id int64) ([]types.Money, error) {
+	return s.sales.TotalByManager(ctx, id)
+}
+
+func (s *Service) SaleByID(ctx context.Context, id int64) (*types.Sale, error) {
+	return s.sales.ByID(ctx, id)
+}
 
+// Receipt collects what is printed on the receipt of the sale.
+func (s *Service) Receipt(ctx context.Context, saleID int64) (*receipts.Receipt, error) {
+	sale, err := s.sales.ByID(ctx, saleID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return items, nil
-		}
-		return nil, types.ErrInternal
+		return nil, err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		item := &types.Product{}
-		err = rows.Scan(&item.ID, &item.Name, &item.Price, &item.Qty)
+	receipt := &receipts.Receipt{Store: s.receipt, Sale: sale}
+	if sale.ManagerID != 0 {
+		manager, err := s.managers.ByID(ctx, sale.ManagerID)
 		if err != nil {
-			log.Print(err)
 			return nil, err
 		}
-		items = append(items, item)
+		receipt.Manager = manager.Name
 	}
+	return receipt, nil
+}
 
-	return items, nil
+func (s *Service) Sales(ctx context.Context, filter *types.SaleFilter) (*types.SalePage, error) {
+	return s.sales.List(ctx, filter)
 }
 
-func (s *Service) RemoveProductByID(ctx context.Context, id int64) (err error) {
-	_, err = s.pool.Exec(ctx, `DELETE FROM products WHERE id = $1`, id)
-	if err != nil {
-		log.Print(err)
-		return types.ErrInternal
+// Reserve holds products for configured TTL.
+func (s *Service) Reserve(ctx context.Context, reservation *types.Reservation) (*types.Reservation, error) {
+	if len(reservation.Positions) == 0 {
+		return nil, types.ErrInvalidPosition
+	}
+	for _, position := range reservation.Positions {
+		if position == nil || position.ProductID == 0 || position.Qty <= 0 {
+			return nil, types.ErrInvalidPosition
+		}
 	}
 
-	return nil
+	return s.reserved.Create(ctx, reservation, s.holdTTL)
+}
+
+func (s *Service) Reservation(ctx context.Context, id int64) (*types.Reservation, error) {
+	return s.reserved.ByID(ctx, id)
+}
+
+func (s *Service) ReleaseReservation(ctx context.Context, id int64) error {
+	return s.reserved.Delete(ctx, id)
 }
 
-func (s *Service) RemoveCustomerByID(ctx context.Context, id int64) (err error) {
-	_, err = s.pool.Exec(ctx, `DELETE FROM customers WHERE id = $1`, id)
+// ConfirmReservation makes sale of reserved products, zero managerID keeps
+// manager of the reservation.
+func (s *Service) ConfirmReservation(ctx context.Context, id, managerID int64) (*types.Sale, error) {
+	sale, err := s.reserved.Confirm(ctx, id, managerID)
 	if err != nil {
-		log.Print(err)
-		return types.ErrInternal
+		return nil, err
 	}
 
-	return nil
+	s.alertLowStock(sale)
+	return sale, nil
 }
 
-func (s *Service) Customers(ctx context.Context) ([]*types.Customer, error) {
-	items := make([]*types.Customer, 0)
-	sql := `SELECT id, name, phone, active, created FROM customers WHERE active = TRUE ORDER BY ID LIMIT 500`
-	rows, err := s.pool.Query(ctx, sql)
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			return items, nil
+// SweepReservations releases expired reservations every interval until ctx is done.
+func (s *Service) SweepReservations(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := s.reserved.DeleteExpired(ctx)
+			if err != nil {
+				logger.Errorf("sweep reservations: %v", err)
+				continue
+			}
+			if count > 0 {
+				logger.Infof("released %d expired reservations", count)
+			}
 		}
-		return nil, types.ErrInternal
 	}
-	defer rows.Close()
+}
 
-	for rows.Next() {
-		item := &types.Customer{}
-		err = rows.Scan(&item.ID, &item.Name, &item.Phone, &item.Active, &item.Created)
-		if err != nil {
-			log.Print(err)
-			return nil, err
+// AssignSale makes manager responsible for the sale customer made by checkout.
+func (s *Service) AssignSale(ctx context.Context, saleID, managerID int64) (*types.Sale, error) {
+	return s.sales.AssignManager(ctx, saleID, managerID)
+}
+
+// MakeReturn returns given positions of the sale back to stock,
+// without positions everything which is not returned yet is returned.
+func (s *Service) MakeReturn(ctx context.Context, ret *types.Return) (*types.Return, error) {
+	for _, position := range ret.Positions {
+		if position == nil || position.SalePositionID == 0 || position.Qty <= 0 {
+			return nil, types.ErrInvalidPosition
 		}
-		items = append(items, item)
 	}
 
-	return items, nil
+	return s.returns.Create(ctx, ret)
+}
+
+func (s *Service) Returns(ctx context.Context, saleID int64) ([]*types.Return, error) {
+	if _, err := s.sales.ByID(ctx, saleID); err != nil {
+		return nil, err
+	}
+	return s.returns.BySale(ctx, saleID)
+}
+
+func (s *Service) Products(ctx context.Context, filter *types.ProductFilter) (*types.ProductPage, error) {
+	return s.products.List(ctx, filter)
+}
+
+func (s *Service) ProductByID(ctx context.Context, id int64) (*types.Product, error) {
+	return s.products.ByID(ctx, id)
+}
+
+// RemoveProductByID only deactivates product, so that sales history stays intact.
+func (s *Service) RemoveProductByID(ctx context.Context, id int64) (*types.Product, error) {
+	return s.products.ChangeActive(ctx, id, false)
+}
+
+func (s *Service) RestoreProductByID(ctx context.Context, id int64) (*types.Product, error) {
+	return s.products.ChangeActive(ctx, id, true)
+}
+
+func (s *Service) PurgeProductByID(ctx context.Context, id int64) error {
+	return s.products.Purge(ctx, id)
+}
+
+func (s *Service) RemoveCustomerByID(ctx context.Context, id int64) error {
+	_, err := s.customers.Delete(ctx, id)
+	return err
+}
+
+func (s *Service) Customers(ctx context.Context, filter *types.CustomerFilter) (*types.CustomerPage, error) {
+	return s.customers.List(ctx, filter)
 }
 
 func (s *Service) ChangeCustomer(ctx context.Context, customer *types.Customer) (*types.Customer, error) {
-	sql := `UPDATE customers SET name = $2, phone = $3, active = $4  WHERE id = $1 RETURNING name, phone, active`
-	if err := s.pool.QueryRow(
-		ctx,
-		sql,
-		customer.ID,
-		customer.Name,
-		customer.Phone,
-		customer.Active).
-		Scan(&customer.Name, &customer.Phone, &customer.Active); err != nil {
-		log.Print(err)
-		return nil, types.ErrInternal
-	}
-
-	return customer, nil
+	return s.customers.Change(ctx, customer)
 }
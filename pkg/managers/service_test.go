@@ -0,0 +1,409 @@
+package managers
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/alerts"
+	"github.com/bdaler/crud/pkg/config"
+	"github.com/bdaler/crud/pkg/repository/memory"
+	"github.com/bdaler/crud/pkg/types"
+	"testing"
+	"time"
+)
+
+const adminID = 1
+
+func newTestService(cfg *config.Config) *Service {
+	return newTestServiceWithNotifier(cfg, &alerts.LogNotifier{})
+}
+
+func newTestServiceWithNotifier(cfg *config.Config, notifier alerts.Notifier) *Service {
+	db := memory.NewDB()
+	return NewService(
+		memory.NewManagerRepository(db),
+		memory.NewCustomerRepository(db),
+		memory.NewProductRepository(db),
+		memory.NewSaleRepository(db),
+		memory.NewReturnRepository(db),
+		memory.NewReservationRepository(db),
+		memory.NewStockRepository(db),
+		memory.NewCategoryRepository(db),
+		memory.NewPriceRepository(db),
+		memory.NewPromoRepository(db),
+		notifier,
+		memory.NewTokenRepository(db),
+		cfg,
+	)
+}
+
+func saveProduct(t *testing.T, svc *Service, name string, price int64, qty int) *types.Product {
+	t.Helper()
+	product, err := svc.SaveProduct(context.Background(), &types.Product{
+		Name:   name,
+		Price:  types.Money{Amount: price},
+		Qty:    qty,
+		Active: true,
+	}, adminID)
+	if err != nil {
+		t.Fatalf("SaveProduct() error = %v", err)
+	}
+	return product
+}
+
+func TestService_MakeSale(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(config.Default())
+	bread := saveProduct(t, svc, "Bread", 300, 10)
+	milk := saveProduct(t, svc, "Milk", 900, 2)
+
+	sale, err := svc.MakeSale(ctx, &types.Sale{
+		ManagerID: adminID,
+		Positions: []*types.SalePosition{
+			{ProductID: bread.ID, Qty: 3},
+			{ProductID: milk.ID, Qty: 2},
+		},
+	})
+	if err != nil {
+		t.Fatalf("MakeSale() error = %v", err)
+	}
+	if want := types.NewMoney(2700, "TJS"); sale.Total != want {
+		t.Errorf("MakeSale() total = %v, want %v", sale.Total, want)
+	}
+	assertQty(t, svc, bread.ID, 7)
+	assertQty(t, svc, milk.ID, 0)
+}
+
+func TestService_MakeSale_allOrNothing(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(config.Default())
+	bread := saveProduct(t, svc, "Bread", 300, 10)
+	milk := saveProduct(t, svc, "Milk", 900, 2)
+
+	tests := []struct {
+		name      string
+		positions []*types.SalePosition
+		err       error
+	}{
+		{"not enough of the last", []*types.SalePosition{{ProductID: bread.ID, Qty: 5}, {ProductID: milk.ID, Qty: 3}}, types.ErrNotEnoughQty},
+		{"unknown product", []*types.SalePosition{{ProductID: bread.ID, Qty: 5}, {ProductID: -1, Qty: 1}}, types.ErrNotFound},
+		{"invalid qty", []*types.SalePosition{{ProductID: bread.ID, Qty: 5}, {ProductID: milk.ID, Qty: 0}}, types.ErrInvalidPosition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.MakeSale(ctx, &types.Sale{ManagerID: adminID, Positions: tt.positions})
+			if err != tt.err {
+				t.Fatalf("MakeSale() error = %v, want %v", err, tt.err)
+			}
+			assertQty(t, svc, bread.ID, 10)
+			assertQty(t, svc, milk.ID, 2)
+		})
+	}
+
+	page, err := svc.Sales(ctx, &types.SaleFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 0 {
+		t.Errorf("Sales() = %d sales, want none", len(page.Items))
+	}
+}
+
+func assertQty(t *testing.T, svc *Service, productID int64, want int) {
+	t.Helper()
+	product, err := svc.ProductByID(context.Background(), productID)
+	if err != nil {
+		t.Fatalf("ProductByID() error = %v", err)
+	}
+	if product.Qty != want {
+		t.Errorf("product %d qty = %d, want %d", productID, product.Qty, want)
+	}
+}
+
+func TestService_Token(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(config.Default())
+
+	if _, err := svc.Token(ctx, "+992000000001", "wrong"); err != types.ErrInvalidPassword {
+		t.Errorf("Token() error = %v, want %v", err, types.ErrInvalidPassword)
+	}
+	if _, err := svc.Token(ctx, "+992000000099", "secret"); err != types.ErrInvalidPassword {
+		t.Errorf("Token() unknown phone error = %v, want %v", err, types.ErrInvalidPassword)
+	}
+
+	token, err := svc.Token(ctx, "+992000000001", "secret")
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	id, err := svc.IDByToken(ctx, token)
+	if err != nil || id != adminID {
+		t.Errorf("IDByToken() = %d, %v, want %d", id, err, adminID)
+	}
+	if id, err = svc.IDByToken(ctx, "unknown"); err != nil || id != 0 {
+		t.Errorf("IDByToken() unknown = %d, %v, want 0", id, err)
+	}
+}
+
+func TestService_TokenExpired(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Default()
+	cfg.Tokens.ManagerTTL = config.Duration(time.Millisecond)
+	svc := newTestService(cfg)
+
+	token, err := svc.Token(ctx, "+992000000001", "secret")
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	if _, err = svc.IDByToken(ctx, token); err != types.ErrTokenExpired {
+		t.Errorf("IDByToken() error = %v, want %v", err, types.ErrTokenExpired)
+	}
+}
+
+func TestService_MakeSale_reserved(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(config.Default())
+	bread := saveProduct(t, svc, "Bread", 300, 10)
+	const buyerID, otherID = 100, 200
+
+	reserve := func(qty int) *types.Reservation {
+		t.Helper()
+		reservation, err := svc.Reserve(ctx, &types.Reservation{
+			CustomerID: buyerID,
+			Positions:  []*types.ReservationPosition{{ProductID: bread.ID, Qty: qty}},
+		})
+		if err != nil {
+			t.Fatalf("Reserve() error = %v", err)
+		}
+		return reservation
+	}
+	sell := func(customerID int64, qty int) error {
+		_, err := svc.MakeSale(ctx, &types.Sale{
+			ManagerID:  adminID,
+			CustomerID: customerID,
+			Positions:  []*types.SalePosition{{ProductID: bread.ID, Qty: qty}},
+		})
+		return err
+	}
+	assertAvailable := func(qty, available int) {
+		t.Helper()
+		product, err := svc.ProductByID(ctx, bread.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if product.Qty != qty || product.Available != available {
+			t.Errorf("qty = %d, available = %d, want %d, %d", product.Qty, product.Available, qty, available)
+		}
+	}
+
+	first := reserve(4)
+	for _, customerID := range []int64{0, otherID} {
+		if err := sell(customerID, 7); err != types.ErrNotEnoughQty {
+			t.Errorf("MakeSale() by %d error = %v, want %v", customerID, err, types.ErrNotEnoughQty)
+		}
+	}
+
+	// the buyer takes the reserved quantity, it's held no more
+	if err := sell(buyerID, 4); err != nil {
+		t.Fatalf("MakeSale() error = %v", err)
+	}
+	assertAvailable(6, 6)
+	if _, err := svc.Reservation(ctx, first.ID); err != types.ErrNotFound {
+		t.Errorf("Reservation() error = %v, want used up reservation gone", err)
+	}
+
+	// partly bought reservation keeps the rest
+	second := reserve(4)
+	if err := sell(buyerID, 1); err != nil {
+		t.Fatalf("MakeSale() error = %v", err)
+	}
+	assertAvailable(5, 2)
+	reservation, err := svc.Reservation(ctx, second.ID)
+	if err != nil {
+		t.Fatalf("Reservation() error = %v", err)
+	}
+	if len(reservation.Positions) != 1 || reservation.Positions[0].Qty != 3 {
+		t.Errorf("Reservation() positions = %+v, want 3 left", reservation.Positions)
+	}
+
+	// buying everything never makes available negative
+	if err = sell(buyerID, 5); err != nil {
+		t.Fatalf("MakeSale() error = %v", err)
+	}
+	assertAvailable(0, 0)
+}
+
+func TestService_SaveProduct_validation(t *testing.T) {
+	svc := newTestService(config.Default())
+
+	tests := []struct {
+		name    string
+		product *types.Product
+		field   string
+	}{
+		{"empty name", &types.Product{Price: types.NewMoney(100, "TJS")}, "name"},
+		{"blank name", &types.Product{Name: "  ", Price: types.NewMoney(100, "TJS")}, "name"},
+		{"negative qty", &types.Product{Name: "Bread", Price: types.NewMoney(100, "TJS"), Qty: -1}, "qty"},
+		{"zero price", &types.Product{Name: "Bread", Price: types.NewMoney(0, "TJS")}, "price"},
+		{"negative price", &types.Product{Name: "Bread", Price: types.NewMoney(-100, "TJS")}, "price"},
+		{"negative reorder level", &types.Product{Name: "Bread", Price: types.NewMoney(100, "TJS"), ReorderLevel: -1}, "reorder_level"},
+		{"bad currency", &types.Product{Name: "Bread", Price: types.NewMoney(100, "tjs")}, "price.currency"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.SaveProduct(context.Background(), tt.product, adminID)
+			validation, ok := err.(*types.ValidationError)
+			if !ok {
+				t.Fatalf("SaveProduct() error = %v, want validation error", err)
+			}
+			if len(validation.Fields) != 1 || validation.Fields[0].Field != tt.field {
+				t.Errorf("SaveProduct() fields = %v, want %q", validation.Fields, tt.field)
+			}
+		})
+	}
+}
+
+type chanNotifier chan *alerts.LowStock
+
+func (n chanNotifier) NotifyLowStock(ctx context.Context, alert *alerts.LowStock) error {
+	n <- alert
+	return nil
+}
+
+func TestService_MakeSale_lowStockAlert(t *testing.T) {
+	ctx := context.Background()
+	notifier := make(chanNotifier, 10)
+	svc := newTestServiceWithNotifier(config.Default(), notifier)
+	bread, err := svc.SaveProduct(ctx, &types.Product{
+		Name:         "Bread",
+		Price:        types.Money{Amount: 300},
+		Qty:          10,
+		ReorderLevel: 5,
+		Active:       true,
+	}, adminID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sell := func(qty ...int) {
+		t.Helper()
+		sale := &types.Sale{ManagerID: adminID}
+		for _, q := range qty {
+			sale.Positions = append(sale.Positions, &types.SalePosition{ProductID: bread.ID, Qty: q})
+		}
+		if _, err := svc.MakeSale(ctx, sale); err != nil {
+			t.Fatalf("MakeSale() error = %v", err)
+		}
+	}
+
+	sell(2, 2) // 6 left, above the level
+	sell(1, 3) // 2 left, crossed by the first position
+	sell(1)    // low already
+
+	select {
+	case alert := <-notifier:
+		if alert.ProductID != bread.ID || alert.Qty != 2 || alert.ReorderLevel != 5 || alert.Name != "Bread" {
+			t.Errorf("alert = %+v, want Bread qty 2 level 5", alert)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no low stock alert")
+	}
+	select {
+	case alert := <-notifier:
+		t.Errorf("unexpected alert %+v", alert)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestService_SaveProduct_keepsPromotion(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(config.Default())
+	bread := saveProduct(t, svc, "Bread", 1000, 10)
+
+	started := time.Now().Add(20 * time.Millisecond)
+	end := started.Add(time.Hour)
+	promo, err := svc.SchedulePrice(ctx, &types.ProductPrice{
+		ProductID:     bread.ID,
+		Price:         types.NewMoney(800, ""),
+		EffectiveFrom: started,
+		EffectiveTo:   &end,
+	})
+	if err != nil {
+		t.Fatalf("SchedulePrice() error = %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	bread.Name = "White bread"
+	bread.Qty = 12
+	bread.Price = types.NewMoney(1000, "TJS")
+	saved, err := svc.SaveProduct(ctx, bread, adminID)
+	if err != nil {
+		t.Fatalf("SaveProduct() error = %v", err)
+	}
+	if want := types.NewMoney(800, "TJS"); saved.Price != want {
+		t.Errorf("SaveProduct() price = %v, want promotion %v", saved.Price, want)
+	}
+
+	prices, err := svc.ProductPrices(ctx, bread.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, price := range prices {
+		if price.ID == promo.ID && (price.EffectiveTo == nil || !price.EffectiveTo.Equal(end.UTC())) {
+			t.Errorf("promotion ends at %v, want %v", price.EffectiveTo, end.UTC())
+		}
+	}
+	if len(prices) != 2 {
+		t.Errorf("ProductPrices() = %d prices, want regular and promotion", len(prices))
+	}
+}
+
+type blockingNotifier struct {
+	started chan struct{}
+	err     chan error
+}
+
+func (n *blockingNotifier) NotifyLowStock(ctx context.Context, alert *alerts.LowStock) error {
+	close(n.started)
+	<-ctx.Done()
+	n.err <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestService_Shutdown(t *testing.T) {
+	ctx := context.Background()
+	notifier := &blockingNotifier{started: make(chan struct{}), err: make(chan error, 1)}
+	svc := newTestServiceWithNotifier(config.Default(), notifier)
+	bread, err := svc.SaveProduct(ctx, &types.Product{
+		Name:         "Bread",
+		Price:        types.Money{Amount: 300},
+		Qty:          3,
+		ReorderLevel: 2,
+		Active:       true,
+	}, adminID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sale := &types.Sale{ManagerID: adminID, Positions: []*types.SalePosition{{ProductID: bread.ID, Qty: 2}}}
+	if _, err := svc.MakeSale(ctx, sale); err != nil {
+		t.Fatalf("MakeSale() error = %v", err)
+	}
+	select {
+	case <-notifier.started:
+	case <-time.After(time.Second):
+		t.Fatal("no low stock alert")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	svc.Shutdown(shutdownCtx)
+
+	select {
+	case err := <-notifier.err:
+		if err != context.Canceled {
+			t.Errorf("alert context error = %v, want %v", err, context.Canceled)
+		}
+	default:
+		t.Error("Shutdown() returned before alert finished")
+	}
+}
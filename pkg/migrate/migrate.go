@@ -0,0 +1,256 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var ErrNoMigrations = errors.New("nothing to migrate")
+
+// lockID is an arbitrary key for pg_advisory_lock, so that two instances
+// can't run migrations at the same time.
+const lockID = 7343417
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version int64      `json:"version"`
+	Name    string     `json:"name"`
+	Applied *time.Time `json:"applied"`
+}
+
+// Load reads migrations from fsys, every version must have both up and down files.
+func Load(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		item, ok := byVersion[version]
+		if !ok {
+			item = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = item
+		}
+		if item.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has different names: %s and %s", version, item.Name, match[2])
+		}
+		if match[3] == "up" {
+			item.Up = string(data)
+		} else {
+			item.Down = string(data)
+		}
+	}
+
+	items := make([]*Migration, 0, len(byVersion))
+	for _, item := range byVersion {
+		if item.Up == "" || item.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", item.Version, item.Name)
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Version < items[j].Version })
+
+	return items, nil
+}
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []*Migration
+}
+
+func NewMigrator(pool *pgxpool.Pool, migrations []*Migration) *Migrator {
+	return &Migrator{pool: pool, migrations: migrations}
+}
+
+// Up applies all pending migrations in order, each in its own transaction.
+func (m *Migrator) Up(ctx context.Context) (applied []*Migration, err error) {
+	err = m.locked(ctx, func(conn *pgxpool.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range m.migrations {
+			if _, ok := versions[item.Version]; ok {
+				continue
+			}
+
+			err = apply(ctx, conn, item.Up,
+				`INSERT INTO schema_migrations(version, name) VALUES ($1, $2)`, item.Version, item.Name)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", item.Version, item.Name, err)
+			}
+			logger.Infof("applied migration %d_%s", item.Version, item.Name)
+			applied = append(applied, item)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the latest applied migration.
+func (m *Migrator) Down(ctx context.Context) (reverted *Migration, err error) {
+	err = m.locked(ctx, func(conn *pgxpool.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			item := m.migrations[i]
+			if _, ok := versions[item.Version]; !ok {
+				continue
+			}
+
+			err = apply(ctx, conn, item.Down, `DELETE FROM schema_migrations WHERE version = $1`, item.Version)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", item.Version, item.Name, err)
+			}
+			logger.Infof("reverted migration %d_%s", item.Version, item.Name)
+			reverted = item
+			return nil
+		}
+
+		return ErrNoMigrations
+	})
+
+	return reverted, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	if err = ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	versions, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*Status, 0, len(m.migrations))
+	for _, item := range m.migrations {
+		status := &Status{Version: item.Version, Name: item.Name}
+		if applied, ok := versions[item.Version]; ok {
+			status.Applied = &applied
+		}
+		items = append(items, status)
+	}
+
+	return items, nil
+}
+
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgxpool.Conn) error) (err error) {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return err
+	}
+	defer func() {
+		if _, unlockErr := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID); unlockErr != nil {
+			logger.Error(unlockErr)
+		}
+	}()
+
+	if err = ensureTable(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func ensureTable(ctx context.Context, conn *pgxpool.Conn) error {
+	_, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations
+	(
+		version BIGINT PRIMARY KEY,
+		name    TEXT      NOT NULL,
+		applied TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+func appliedVersions(ctx context.Context, conn *pgxpool.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.Query(ctx, `SELECT version, applied FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var applied time.Time
+		if err = rows.Scan(&version, &applied); err != nil {
+			return nil, err
+		}
+		versions[version] = applied
+	}
+
+	return versions, rows.Err()
+}
+
+// apply runs migration sql and bookkeeping statement in one transaction.
+func apply(ctx context.Context, conn *pgxpool.Conn, sql, bookkeeping string, args ...interface{}) (err error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(ctx); rbErr != nil && rbErr != pgx.ErrTxClosed {
+				logger.Error(rbErr)
+			}
+		}
+	}()
+
+	if _, err = tx.Exec(ctx, sql); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
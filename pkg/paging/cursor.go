@@ -0,0 +1,124 @@
+// Package paging implements opaque cursors for keyset pagination.
+package paging
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/bdaler/crud/pkg/types"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// Cursor points right after the last returned row: its value of sort column and id.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v,omitempty"`
+	ID    int64  `json:"id"`
+}
+
+func Encode(cursor *Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode returns nil cursor for empty string and types.ErrInvalidCursor
+// when value is malformed or was issued for another sort.
+func Decode(value, sort string) (*Cursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, types.ErrInvalidCursor
+	}
+
+	cursor := &Cursor{}
+	if err = json.Unmarshal(data, cursor); err != nil || cursor.Sort != sort {
+		return nil, types.ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+func Limit(limit int) int {
+	if limit <= 0 {
+		return DefaultLimit
+	}
+	if limit > MaxLimit {
+		return MaxLimit
+	}
+	return limit
+}
+
+func IntValue(cursor *Cursor) (int64, error) {
+	value, err := strconv.ParseInt(cursor.Value, 10, 64)
+	if err != nil {
+		return 0, types.ErrInvalidCursor
+	}
+	return value, nil
+}
+
+func TimeValue(cursor *Cursor) (time.Time, error) {
+	value, err := time.Parse(time.RFC3339Nano, cursor.Value)
+	if err != nil {
+		return time.Time{}, types.ErrInvalidCursor
+	}
+	return value, nil
+}
+
+func FormatTime(value time.Time) string {
+	return value.Format(time.RFC3339Nano)
+}
+
+func ProductCursor(item *types.Product, sort string) *Cursor {
+	cursor := &Cursor{Sort: sort, ID: item.ID}
+	switch sort {
+	case types.SortName:
+		cursor.Value = item.Name
+	case types.SortPrice:
+		cursor.Value = strconv.FormatInt(item.Price.Amount, 10)
+	case types.SortCreated:
+		cursor.Value = FormatTime(item.Created)
+	}
+	return cursor
+}
+
+func CustomerCursor(item *types.Customer, sort string) *Cursor {
+	cursor := &Cursor{Sort: sort, ID: item.ID}
+	switch sort {
+	case types.SortName:
+		cursor.Value = item.Name
+	case types.SortCreated:
+		cursor.Value = FormatTime(item.Created)
+	}
+	return cursor
+}
+
+// SortValue converts cursor value back to the type of sort column,
+// nil is returned when sorting by id.
+func SortValue(cursor *Cursor) (interface{}, error) {
+	switch cursor.Sort {
+	case types.SortName:
+		return cursor.Value, nil
+	case types.SortPrice:
+		return IntValue(cursor)
+	case types.SortCreated:
+		return TimeValue(cursor)
+	default:
+		return nil, nil
+	}
+}
+
+func SaleCursor(item *types.Sale) *Cursor {
+	return &Cursor{Sort: types.SortCreated, Value: FormatTime(item.Created), ID: item.ID}
+}
+
+func MovementCursor(item *types.StockMovement) *Cursor {
+	return &Cursor{Sort: types.SortID, ID: item.ID}
+}
@@ -0,0 +1,86 @@
+package receipts
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+)
+
+const (
+	fontSize = 9.0
+	leading  = 11.0
+	margin   = 14.0
+	// advance of every Courier glyph is 600 units of 1000
+	charWidth = fontSize * 0.6
+)
+
+// PDF renders the receipt in Courier on a single page as long as the receipt,
+// like a printer roll. Standard fonts only cover Latin-1, other characters are
+// printed as "?".
+func PDF(receipt *Receipt) []byte {
+	lines := Lines(receipt)
+	width := float64(receipt.Store.Width)*charWidth + 2*margin
+	height := float64(len(lines))*leading + 2*margin
+
+	content := &bytes.Buffer{}
+	fmt.Fprintf(content, "BT\n/F1 %.0f Tf\n%.0f TL\n%.2f %.2f Td\n", fontSize, leading, margin, height-margin-fontSize)
+	for _, line := range lines {
+		content.WriteString(pdfString(line))
+		content.WriteString(" Tj T*\n")
+	}
+	content.WriteString("ET\n")
+
+	stream := &bytes.Buffer{}
+	zw := zlib.NewWriter(stream)
+	// writes to bytes.Buffer don't fail
+	_, _ = zw.Write(content.Bytes())
+	_ = zw.Close()
+
+	w := &pdfWriter{}
+	w.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	w.object("<< /Type /Catalog /Pages 2 0 R >>")
+	w.object("<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	w.object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+		"/Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>", width, height))
+	w.object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	w.object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", stream.Len(), stream.Bytes()))
+	return w.finish()
+}
+
+// pdfString encodes text as literal string in WinAnsiEncoding.
+func pdfString(text string) string {
+	result := make([]byte, 0, len(text)+2)
+	result = append(result, '(')
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			result = append(result, '\\', byte(r))
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			result = append(result, byte(r))
+		default:
+			result = append(result, '?')
+		}
+	}
+	return string(append(result, ')'))
+}
+
+// pdfWriter numbers objects from 1 in the order they are written.
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (w *pdfWriter) object(body string) {
+	w.offsets = append(w.offsets, w.buf.Len())
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", len(w.offsets), body)
+}
+
+func (w *pdfWriter) finish() []byte {
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, offset := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.offsets)+1, xref)
+	return w.buf.Bytes()
+}
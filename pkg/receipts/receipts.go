@@ -0,0 +1,189 @@
+// Package receipts renders sale receipts as plain text for thermal printers and as PDF.
+package receipts
+
+import (
+	"fmt"
+	"github.com/bdaler/crud/pkg/config"
+	"github.com/bdaler/crud/pkg/types"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Receipt is everything printed for a sale, Manager is empty for sales made
+// without one.
+type Receipt struct {
+	Store   config.Receipt
+	Sale    *types.Sale
+	Manager string
+}
+
+// Text renders the receipt as lines of Store.Width characters.
+func Text(receipt *Receipt) []byte {
+	return []byte(strings.Join(Lines(receipt), "\n") + "\n")
+}
+
+// Lines lays out the receipt, every line fits into Store.Width characters.
+func Lines(receipt *Receipt) []string {
+	l := &layout{width: receipt.Store.Width}
+	sale := receipt.Sale
+
+	l.center(receipt.Store.StoreName)
+	l.center(receipt.Store.StoreAddress)
+	l.center(receipt.Store.StorePhone)
+	l.rule()
+	l.pair(fmt.Sprintf("Sale #%d", sale.ID), sale.Created.Format("2006-01-02 15:04"))
+	if receipt.Manager != "" {
+		l.text("Manager: " + receipt.Manager)
+	}
+	if sale.Customer != nil {
+		l.text("Customer: " + sale.Customer.Name)
+	}
+	l.rule()
+
+	subtotal := types.Money{Currency: sale.Currency}
+	for _, position := range sale.Positions {
+		name := position.ProductName
+		if name == "" {
+			name = fmt.Sprintf("Product #%d", position.ProductID)
+		}
+		l.text(name)
+
+		line, err := position.Price.Mul(int64(position.Qty))
+		if err == nil {
+			subtotal, err = subtotal.Add(line)
+		}
+		if err != nil {
+			// totals of stored sales are checked on create
+			continue
+		}
+		l.pair(fmt.Sprintf("  %d x %s", position.Qty, amount(position.Price)), amount(line))
+		if !position.Discount.IsZero() {
+			l.pair("  Discount", "-"+amount(position.Discount))
+		}
+		if position.TaxRate != 0 {
+			label := "  Tax " + rate(position.TaxRate)
+			if position.TaxInclusive {
+				label += " incl."
+			}
+			l.pair(label, amount(position.Tax))
+		}
+		if position.ReturnedQty != 0 {
+			l.pair(fmt.Sprintf("  Returned %d", position.ReturnedQty), "")
+		}
+	}
+	l.rule()
+
+	l.pair("Subtotal", amount(subtotal))
+	if !sale.Discount.IsZero() {
+		label := "Discount"
+		if sale.PromoCode != "" {
+			label += " (" + sale.PromoCode + ")"
+		}
+		l.pair(label, "-"+amount(sale.Discount))
+	}
+	if !sale.Tax.IsZero() {
+		l.pair("Tax", amount(sale.Tax))
+	}
+	l.pair("TOTAL", sale.Total.String())
+	if !sale.Returned.IsZero() {
+		l.pair("Returned", "-"+amount(sale.Returned))
+	}
+	for _, payment := range sale.Payments {
+		l.pair(tenders[payment.Tender], amount(payment.Tendered))
+		if !payment.Change.IsZero() {
+			l.pair("Change", amount(payment.Change))
+		}
+	}
+	if !sale.Due.IsZero() {
+		l.pair("Due", amount(sale.Due))
+	}
+	if sale.Status == types.SaleVoided {
+		l.rule()
+		l.center("VOIDED")
+	}
+	l.rule()
+	l.center("Thank you!")
+
+	return l.lines
+}
+
+var tenders = map[string]string{
+	types.TenderCash:        "Cash",
+	types.TenderCard:        "Card",
+	types.TenderTransfer:    "Transfer",
+	types.TenderStoreCredit: "Store credit",
+}
+
+// amount formats money without currency, it's printed once with the total.
+func amount(m types.Money) string {
+	return strings.TrimSuffix(m.String(), " "+m.Currency)
+}
+
+// rate formats basis points as percent, e.g. 1250 as "12.5%".
+func rate(basisPoints int) string {
+	return strconv.FormatFloat(float64(basisPoints)/100, 'f', -1, 64) + "%"
+}
+
+type layout struct {
+	width int
+	lines []string
+}
+
+// text wraps value by words, words longer than the line are cut.
+func (l *layout) text(value string) {
+	line := ""
+	for _, word := range strings.Fields(value) {
+		for utf8.RuneCountInString(word) > l.width {
+			if line != "" {
+				l.lines = append(l.lines, line)
+				line = ""
+			}
+			runes := []rune(word)
+			l.lines = append(l.lines, string(runes[:l.width]))
+			word = string(runes[l.width:])
+		}
+		switch {
+		case line == "":
+			line = word
+		case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= l.width:
+			line += " " + word
+		default:
+			l.lines = append(l.lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		l.lines = append(l.lines, line)
+	}
+}
+
+func (l *layout) center(value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	if n := utf8.RuneCountInString(value); n < l.width {
+		l.lines = append(l.lines, strings.Repeat(" ", (l.width-n)/2)+value)
+		return
+	}
+	l.text(value)
+}
+
+// pair puts label on the left and value on the right, label goes to a line
+// of its own when both don't fit.
+func (l *layout) pair(label, value string) {
+	gap := l.width - utf8.RuneCountInString(label) - utf8.RuneCountInString(value)
+	if gap < 1 {
+		l.text(label)
+		label, gap = "", l.width-utf8.RuneCountInString(value)
+	}
+	if gap < 0 {
+		gap = 0
+	}
+	l.lines = append(l.lines, strings.TrimRight(label+strings.Repeat(" ", gap)+value, " "))
+}
+
+func (l *layout) rule() {
+	l.lines = append(l.lines, strings.Repeat("-", l.width))
+}
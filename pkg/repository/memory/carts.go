@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/types"
+	"sort"
+)
+
+type CartRepository struct {
+	db *DB
+}
+
+func NewCartRepository(db *DB) *CartRepository {
+	return &CartRepository{db: db}
+}
+
+func (r *CartRepository) ByCustomer(ctx context.Context, customerID int64) (*types.Cart, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	cart := &types.Cart{CustomerID: customerID, Items: make([]*types.CartItem, 0)}
+	for _, item := range r.db.carts[customerID] {
+		copied := *item
+		if product, ok := r.db.products[item.ProductID]; ok {
+			copied.ProductName = product.Name
+			copied.Price = r.db.effectivePrice(product)
+		}
+		cart.Items = append(cart.Items, &copied)
+	}
+	sort.Slice(cart.Items, func(i, j int) bool {
+		if cart.Items[i].Created.Equal(cart.Items[j].Created) {
+			return cart.Items[i].ProductID < cart.Items[j].ProductID
+		}
+		return cart.Items[i].Created.Before(cart.Items[j].Created)
+	})
+
+	if err := cart.CalcTotals(); err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+func (r *CartRepository) SetItem(ctx context.Context, customerID, productID int64, qty int) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.customers[customerID]; !ok {
+		return types.ErrNotFound
+	}
+	if _, ok := r.db.products[productID]; !ok {
+		return types.ErrNotFound
+	}
+
+	items, ok := r.db.carts[customerID]
+	if !ok {
+		items = make(map[int64]*types.CartItem)
+		r.db.carts[customerID] = items
+	}
+	if item, ok := items[productID]; ok {
+		item.Qty = qty
+		return nil
+	}
+	items[productID] = &types.CartItem{ProductID: productID, Qty: qty, Created: now()}
+	return nil
+}
+
+func (r *CartRepository) RemoveItem(ctx context.Context, customerID, productID int64) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.carts[customerID][productID]; !ok {
+		return types.ErrNotFound
+	}
+	delete(r.db.carts[customerID], productID)
+	return nil
+}
+
+func (r *CartRepository) Clear(ctx context.Context, customerID int64) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.carts, customerID)
+	return nil
+}
@@ -0,0 +1,141 @@
+package memory
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/types"
+	"sort"
+)
+
+type CategoryRepository struct {
+	db *DB
+}
+
+func NewCategoryRepository(db *DB) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+func copyCategory(item *types.Category) *types.Category {
+	result := *item
+	if item.TaxRate != nil {
+		rate := *item.TaxRate
+		result.TaxRate = &rate
+	}
+	result.Children = nil
+	return &result
+}
+
+func sortCategories(items []*types.Category) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Name == items[j].Name {
+			return items[i].ID < items[j].ID
+		}
+		return items[i].Name < items[j].Name
+	})
+}
+
+func (r *CategoryRepository) Save(ctx context.Context, category *types.Category) (*types.Category, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if category.ParentID != 0 {
+		if _, ok := r.db.categories[category.ParentID]; !ok {
+			return nil, types.ErrNotFound
+		}
+		if category.ID != 0 && r.db.categorySubtree(category.ID)[category.ParentID] {
+			return nil, types.ErrCategoryCycle
+		}
+	}
+
+	if category.ID == 0 {
+		item := copyCategory(category)
+		item.ID = r.db.nextID()
+		item.Created = now()
+		r.db.categories[item.ID] = item
+		return copyCategory(item), nil
+	}
+
+	item, ok := r.db.categories[category.ID]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	stored := copyCategory(category)
+	stored.Created = item.Created
+	r.db.categories[item.ID] = stored
+	return copyCategory(stored), nil
+}
+
+func (r *CategoryRepository) ByID(ctx context.Context, id int64) (*types.Category, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	item, ok := r.db.categories[id]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	return copyCategory(item), nil
+}
+
+func (r *CategoryRepository) All(ctx context.Context) ([]*types.Category, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	items := make([]*types.Category, 0, len(r.db.categories))
+	for _, item := range r.db.categories {
+		items = append(items, copyCategory(item))
+	}
+	sortCategories(items)
+	return items, nil
+}
+
+func (r *CategoryRepository) Delete(ctx context.Context, id int64) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.categories[id]; !ok {
+		return types.ErrNotFound
+	}
+	for _, item := range r.db.categories {
+		if item.ParentID == id {
+			return types.ErrCategoryNotEmpty
+		}
+	}
+
+	delete(r.db.categories, id)
+	for _, categories := range r.db.productCategories {
+		delete(categories, id)
+	}
+	return nil
+}
+
+func (r *CategoryRepository) ByProduct(ctx context.Context, productID int64) ([]*types.Category, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	items := make([]*types.Category, 0)
+	for categoryID := range r.db.productCategories[productID] {
+		if item, ok := r.db.categories[categoryID]; ok {
+			items = append(items, copyCategory(item))
+		}
+	}
+	sortCategories(items)
+	return items, nil
+}
+
+func (r *CategoryRepository) SetProductCategories(ctx context.Context, productID int64, categoryIDs []int64) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.products[productID]; !ok {
+		return types.ErrNotFound
+	}
+	categories := make(map[int64]bool, len(categoryIDs))
+	for _, categoryID := range categoryIDs {
+		if _, ok := r.db.categories[categoryID]; !ok {
+			return types.ErrNotFound
+		}
+		categories[categoryID] = true
+	}
+
+	r.db.productCategories[productID] = categories
+	return nil
+}
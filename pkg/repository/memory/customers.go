@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/paging"
+	"github.com/bdaler/crud/pkg/repository"
+	"github.com/bdaler/crud/pkg/types"
+	"sort"
+)
+
+type CustomerRepository struct {
+	db *DB
+}
+
+func NewCustomerRepository(db *DB) *CustomerRepository {
+	return &CustomerRepository{db: db}
+}
+
+func copyCustomer(item *types.Customer) *types.Customer {
+	result := *item
+	result.Password = ""
+	return &result
+}
+
+func (r *CustomerRepository) ByID(ctx context.Context, id int64) (*types.Customer, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	item, ok := r.db.customers[id]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	return copyCustomer(item), nil
+}
+
+func (r *CustomerRepository) All(ctx context.Context) ([]*types.Customer, error) {
+	return r.filter(0, func(item *types.Customer) bool { return true }), nil
+}
+
+func (r *CustomerRepository) AllActive(ctx context.Context) ([]*types.Customer, error) {
+	return r.filter(500, func(item *types.Customer) bool { return item.Active }), nil
+}
+
+func (r *CustomerRepository) List(ctx context.Context, filter *types.CustomerFilter) (*types.CustomerPage, error) {
+	if !validSort(filter.Sort, types.SortName, types.SortCreated) {
+		return nil, types.ErrInvalidSort
+	}
+	cursor, err := paging.Decode(filter.After, filter.Sort)
+	if err != nil {
+		return nil, err
+	}
+	limit := paging.Limit(filter.Limit)
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	items := make([]*keyed, 0)
+	for _, item := range r.db.customers {
+		if !item.Active {
+			continue
+		}
+		if filter.Name != "" && !containsFold(item.Name, filter.Name) && !containsFold(item.Phone, filter.Name) {
+			continue
+		}
+		items = append(items, &keyed{key: customerKey(item, filter.Sort), id: item.ID, item: item})
+	}
+
+	items, err = page(items, cursor, filter.Desc, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.CustomerPage{Items: make([]*types.Customer, 0, len(items))}
+	for _, item := range items {
+		result.Items = append(result.Items, copyCustomer(item.item.(*types.Customer)))
+	}
+	if len(result.Items) > limit {
+		result.Items = result.Items[:limit]
+		result.NextCursor = paging.Encode(paging.CustomerCursor(result.Items[limit-1], filter.Sort))
+	}
+
+	return result, nil
+}
+
+func (r *CustomerRepository) filter(limit int, fn func(item *types.Customer) bool) []*types.Customer {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	items := make([]*types.Customer, 0)
+	for _, item := range r.db.customers {
+		if fn(item) {
+			items = append(items, copyCustomer(item))
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+// phoneUsed must be called with mu locked.
+func (r *CustomerRepository) phoneUsed(phone string, exceptID int64) bool {
+	for _, item := range r.db.customers {
+		if item.Phone == phone && item.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *CustomerRepository) Save(ctx context.Context, customer *types.Customer) (*types.Customer, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if r.phoneUsed(customer.Phone, customer.ID) {
+		return nil, types.ErrPhoneUsed
+	}
+
+	if customer.ID == 0 {
+		item := &types.Customer{
+			ID:       r.db.nextID(),
+			Name:     customer.Name,
+			Phone:    customer.Phone,
+			Password: customer.Password,
+			Active:   true,
+			Created:  now(),
+		}
+		r.db.customers[item.ID] = item
+		return copyCustomer(item), nil
+	}
+
+	item, ok := r.db.customers[customer.ID]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	item.Name = customer.Name
+	item.Phone = customer.Phone
+	item.Password = customer.Password
+	return copyCustomer(item), nil
+}
+
+func (r *CustomerRepository) Change(ctx context.Context, customer *types.Customer) (*types.Customer, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	item, ok := r.db.customers[customer.ID]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	if r.phoneUsed(customer.Phone, customer.ID) {
+		return nil, types.ErrPhoneUsed
+	}
+	item.Name = customer.Name
+	item.Phone = customer.Phone
+	item.Active = customer.Active
+	return copyCustomer(item), nil
+}
+
+func (r *CustomerRepository) ChangeActive(ctx context.Context, id int64, active bool) (*types.Customer, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	item, ok := r.db.customers[id]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	item.Active = active
+	return copyCustomer(item), nil
+}
+
+func (r *CustomerRepository) Delete(ctx context.Context, id int64) (*types.Customer, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	item, ok := r.db.customers[id]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	delete(r.db.customers, id)
+	delete(r.db.carts, id)
+	for value, t := range r.db.tokens[repository.Customers] {
+		if t.id == id {
+			delete(r.db.tokens[repository.Customers], value)
+		}
+	}
+	return copyCustomer(item), nil
+}
+
+func (r *CustomerRepository) Credentials(ctx context.Context, phone string) (int64, string, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, item := range r.db.customers {
+		if item.Phone == phone {
+			return item.ID, item.Password, nil
+		}
+	}
+	return 0, "", types.ErrNoSuchUser
+}
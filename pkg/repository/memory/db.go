@@ -0,0 +1,359 @@
+package memory
+
+import (
+	"github.com/bdaler/crud/pkg/repository"
+	"github.com/bdaler/crud/pkg/types"
+	"sort"
+	"sync"
+	"time"
+)
+
+type manager struct {
+	types.Manager
+	roles map[string]bool
+}
+
+type token struct {
+	id     int64
+	expire time.Time
+}
+
+// DB is a thread-safe in-memory storage shared by all memory repositories,
+// single lock keeps operations touching several collections (e.g. sales and stock) atomic.
+type DB struct {
+	mu                sync.RWMutex
+	seq               int64
+	customers         map[int64]*types.Customer
+	managers          map[int64]*manager
+	roles             map[string]bool
+	products          map[int64]*types.Product
+	sales             map[int64]*types.Sale
+	returns           map[int64]*types.Return
+	carts             map[int64]map[int64]*types.CartItem
+	reserved          map[int64]*types.Reservation
+	movements         []*types.StockMovement
+	prices            []*types.ProductPrice
+	promos            map[int64]*types.PromoCode
+	categories        map[int64]*types.Category
+	productCategories map[int64]map[int64]bool // product id -> set of category ids
+	tokens            map[repository.Principal]map[string]*token
+}
+
+// NewDB creates storage seeded with the same admin as migrations/0003_seed_admin.up.sql,
+// otherwise nobody could register managers.
+func NewDB() *DB {
+	db := &DB{
+		customers:         make(map[int64]*types.Customer),
+		managers:          make(map[int64]*manager),
+		roles:             map[string]bool{types.RoleManager: true, types.RoleAdmin: true},
+		products:          make(map[int64]*types.Product),
+		sales:             make(map[int64]*types.Sale),
+		returns:           make(map[int64]*types.Return),
+		carts:             make(map[int64]map[int64]*types.CartItem),
+		reserved:          make(map[int64]*types.Reservation),
+		categories:        make(map[int64]*types.Category),
+		promos:            make(map[int64]*types.PromoCode),
+		productCategories: make(map[int64]map[int64]bool),
+		tokens: map[repository.Principal]map[string]*token{
+			repository.Customers: make(map[string]*token),
+			repository.Managers:  make(map[string]*token),
+		},
+	}
+
+	admin := &manager{
+		Manager: types.Manager{
+			Name:     "vasya",
+			Phone:    "+992000000001",
+			Password: "$2a$10$OaUtjCNv2DT5x/dXcV.P3eYkIPIRtBr/v8Nluwifz6brSkfyXOh6m",
+			Created:  now(),
+		},
+		roles: map[string]bool{types.RoleManager: true, types.RoleAdmin: true},
+	}
+	admin.ID = db.nextID()
+	db.managers[admin.ID] = admin
+
+	return db
+}
+
+// nextID must be called with mu locked, ids are unique across all collections.
+func (db *DB) nextID() int64 {
+	db.seq++
+	return db.seq
+}
+
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+// returnedQty must be called with mu locked.
+func (db *DB) returnedQty(salePositionID int64) int {
+	qty := 0
+	for _, ret := range db.returns {
+		for _, position := range ret.Positions {
+			if position.SalePositionID == salePositionID {
+				qty += position.Qty
+			}
+		}
+	}
+	return qty
+}
+
+// reservedQty must be called with mu locked, only active reservations count.
+// Reservations of exceptCustomerID are skipped, zero skips none.
+func (db *DB) reservedQty(productID, exceptCustomerID int64) int {
+	qty := 0
+	current := now()
+	for _, reservation := range db.reserved {
+		if !reservation.Expire.After(current) {
+			continue
+		}
+		if exceptCustomerID != 0 && reservation.CustomerID == exceptCustomerID {
+			continue
+		}
+		for _, position := range reservation.Positions {
+			if position.ProductID == productID {
+				qty += position.Qty
+			}
+		}
+	}
+	return qty
+}
+
+// useReserved must be called with mu locked, it takes qty of the product bought by
+// the customer out of their active reservations, the oldest first. Reservations
+// left without positions are released.
+func (db *DB) useReserved(customerID, productID int64, qty int) {
+	if customerID == 0 {
+		return
+	}
+	ids := make([]int64, 0)
+	current := now()
+	for id, reservation := range db.reserved {
+		if reservation.CustomerID == customerID && reservation.Expire.After(current) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		reservation := db.reserved[id]
+		positions := reservation.Positions[:0]
+		for _, position := range reservation.Positions {
+			if position.ProductID == productID && qty > 0 {
+				used := position.Qty
+				if used > qty {
+					used = qty
+				}
+				position.Qty -= used
+				qty -= used
+			}
+			if position.Qty > 0 {
+				positions = append(positions, position)
+			}
+		}
+		reservation.Positions = positions
+		if len(positions) == 0 {
+			delete(db.reserved, id)
+		}
+	}
+}
+
+// copyProduct must be called with mu locked, it fills effective price and available quantity.
+func (db *DB) copyProduct(item *types.Product) *types.Product {
+	result := *item
+	result.Price = db.effectivePrice(item)
+	result.Available = item.Qty - db.reservedQty(item.ID, 0)
+	return &result
+}
+
+// priceAt must be called with mu locked, the latest started price wins.
+func (db *DB) priceAt(productID int64, at time.Time) *types.ProductPrice {
+	var result *types.ProductPrice
+	for _, price := range db.prices {
+		if price.ProductID != productID || price.EffectiveFrom.After(at) {
+			continue
+		}
+		if price.EffectiveTo != nil && !price.EffectiveTo.After(at) {
+			continue
+		}
+		if result == nil || !price.EffectiveFrom.Before(result.EffectiveFrom) {
+			result = price
+		}
+	}
+	return result
+}
+
+// effectivePrice must be called with mu locked, item.Price keeps the last
+// regular price and is only a fallback.
+func (db *DB) effectivePrice(item *types.Product) types.Money {
+	if price := db.priceAt(item.ID, now()); price != nil {
+		return price.Price
+	}
+	return item.Price
+}
+
+// changePrice must be called with mu locked, the regular price in effect ends now
+// and the new one starts. Scheduled prices with an end (promotions) are left as they are.
+func (db *DB) changePrice(productID int64, price types.Money, managerID int64) {
+	current := now()
+	for _, item := range db.prices {
+		if item.ProductID != productID || !item.EffectiveFrom.Before(current) {
+			continue
+		}
+		if item.EffectiveTo == nil {
+			end := current
+			item.EffectiveTo = &end
+		}
+	}
+	db.prices = append(db.prices, &types.ProductPrice{
+		ID:            db.nextID(),
+		ProductID:     productID,
+		Price:         price,
+		EffectiveFrom: current,
+		ManagerID:     managerID,
+		Created:       current,
+	})
+}
+
+// createSale must be called with mu locked, it validates everything first
+// so that nothing is applied on failure.
+func (db *DB) createSale(sale *types.Sale) error {
+	requested := make(map[int64]int)
+	for _, position := range sale.Positions {
+		product, ok := db.products[position.ProductID]
+		if !ok {
+			return types.ErrNotFound
+		}
+		if !product.Active {
+			return types.ErrInvalidPosition
+		}
+		// price is the one in effect at the time of sale, not the client supplied one
+		position.Price = db.effectivePrice(product)
+		requested[position.ProductID] += position.Qty
+		// quantity held by others' reservations is not for sale, the buyer's own is
+		if product.Qty-db.reservedQty(product.ID, sale.CustomerID) < requested[position.ProductID] {
+			return types.ErrNotEnoughQty
+		}
+	}
+
+	if sale.PromoCode != "" {
+		if err := db.applyPromo(sale); err != nil {
+			return err
+		}
+	}
+	rules := make(map[int64]types.TaxRule)
+	for productID := range requested {
+		if rule, ok := db.taxRule(productID); ok {
+			rules[productID] = rule
+		}
+	}
+	if err := sale.ApplyTax(rules); err != nil {
+		return err
+	}
+	payments := sale.Payments
+	sale.Payments = make([]*types.Payment, 0, len(payments))
+	if err := sale.CalcTotals(); err != nil {
+		return err
+	}
+	// a sale paid at once must be paid in full
+	if len(payments) != 0 {
+		if err := sale.Pay(payments); err != nil {
+			return err
+		}
+		if !sale.Due.IsZero() {
+			return types.ErrPaymentShort
+		}
+	}
+
+	sale.ID = db.nextID()
+	sale.Created = now()
+	stored := *sale
+	stored.Positions = make([]*types.SalePosition, 0, len(sale.Positions))
+	sale.LowStock = nil
+	for _, position := range sale.Positions {
+		product := db.products[position.ProductID]
+		product.Qty -= position.Qty
+		sale.NoteStock(db.copyProduct(product), position.Qty)
+		position.ID = db.nextID()
+		position.SaleID = sale.ID
+		position.Created = sale.Created
+		copied := *position
+		stored.Positions = append(stored.Positions, &copied)
+		db.recordMovement(&types.StockMovement{
+			ProductID:  position.ProductID,
+			Kind:       types.MovementSale,
+			Qty:        -position.Qty,
+			ManagerID:  sale.ManagerID,
+			CustomerID: sale.CustomerID,
+			SaleID:     sale.ID,
+		})
+	}
+	stored.Payments = nil
+	db.addPayments(&stored, sale.Payments)
+	db.sales[sale.ID] = &stored
+	return nil
+}
+
+// addPayments must be called with mu locked, it stores copies of payments with the sale.
+func (db *DB) addPayments(sale *types.Sale, payments []*types.Payment) {
+	for _, payment := range payments {
+		payment.ID = db.nextID()
+		payment.SaleID = sale.ID
+		payment.Created = now()
+		copied := *payment
+		sale.Payments = append(sale.Payments, &copied)
+	}
+}
+
+// recordMovement must be called with mu locked, along with products.qty change.
+func (db *DB) recordMovement(movement *types.StockMovement) {
+	movement.ID = db.nextID()
+	movement.Created = now()
+	copied := *movement
+	db.movements = append(db.movements, &copied)
+}
+
+// categorySubtree must be called with mu locked, it returns the category and all its descendants.
+func (db *DB) categorySubtree(id int64) map[int64]bool {
+	result := map[int64]bool{id: true}
+	for changed := true; changed; {
+		changed = false
+		for _, category := range db.categories {
+			if result[category.ParentID] && !result[category.ID] {
+				result[category.ID] = true
+				changed = true
+			}
+		}
+	}
+	return result
+}
+
+// taxRule must be called with mu locked, the rate of a product is the nearest
+// one up the tree of each of its categories, the highest of them when categories disagree.
+func (db *DB) taxRule(productID int64) (types.TaxRule, bool) {
+	result := types.TaxRule{}
+	found := false
+	for categoryID := range db.productCategories[productID] {
+		for category, ok := db.categories[categoryID]; ok; category, ok = db.categories[category.ParentID] {
+			if category.TaxRate == nil {
+				continue
+			}
+			if !found || *category.TaxRate > result.Rate {
+				result = types.TaxRule{Rate: *category.TaxRate, Inclusive: category.TaxInclusive}
+				found = true
+			}
+			break
+		}
+	}
+	return result, found
+}
+
+// inCategories must be called with mu locked.
+func (db *DB) inCategories(productID int64, categories map[int64]bool) bool {
+	for categoryID := range db.productCategories[productID] {
+		if categories[categoryID] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/types"
+)
+
+type ManagerRepository struct {
+	db *DB
+}
+
+func NewManagerRepository(db *DB) *ManagerRepository {
+	return &ManagerRepository{db: db}
+}
+
+func (r *ManagerRepository) Create(ctx context.Context, item *types.Manager) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, m := range r.db.managers {
+		if m.Phone == item.Phone {
+			return 0, types.ErrPhoneUsed
+		}
+	}
+
+	roles := make(map[string]bool, len(item.Roles))
+	for _, role := range item.Roles {
+		if !r.db.roles[role] {
+			return 0, types.ErrUnknownRole
+		}
+		roles[role] = true
+	}
+
+	m := &manager{Manager: *item, roles: roles}
+	m.ID = r.db.nextID()
+	m.Created = now()
+	m.Roles = nil
+	r.db.managers[m.ID] = m
+
+	return m.ID, nil
+}
+
+func (r *ManagerRepository) Credentials(ctx context.Context, phone string) (int64, string, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, m := range r.db.managers {
+		if m.Phone == phone {
+			return m.ID, m.Password, nil
+		}
+	}
+	return 0, "", types.ErrNoSuchUser
+}
+
+func (r *ManagerRepository) HasAnyRole(ctx context.Context, id int64, roles ...string) (bool, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	m, ok := r.db.managers[id]
+	if !ok {
+		return false, nil
+	}
+	for _, role := range roles {
+		if m.roles[role] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *ManagerRepository) ByID(ctx context.Context, id int64) (*types.Manager, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	m, ok := r.db.managers[id]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	result := m.Manager
+	result.Password = ""
+	result.Roles = nil
+	return &result, nil
+}
@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"github.com/bdaler/crud/pkg/paging"
+	"github.com/bdaler/crud/pkg/types"
+	"sort"
+	"strings"
+	"time"
+)
+
+// compareKeys compares sort values of the same type, returns -1, 0 or 1.
+func compareKeys(a, b interface{}) int {
+	switch a := a.(type) {
+	case string:
+		return strings.Compare(a, b.(string))
+	case int64:
+		b := b.(int64)
+		if a < b {
+			return -1
+		}
+		if a > b {
+			return 1
+		}
+		return 0
+	case time.Time:
+		b := b.(time.Time)
+		if a.Before(b) {
+			return -1
+		}
+		if a.After(b) {
+			return 1
+		}
+		return 0
+	}
+	return 0
+}
+
+// keyed is an item with its sort value and id.
+type keyed struct {
+	key  interface{}
+	id   int64
+	item interface{}
+}
+
+func compareKeyed(a, b *keyed) int {
+	if a.key != nil {
+		if c := compareKeys(a.key, b.key); c != 0 {
+			return c
+		}
+	}
+	if a.id < b.id {
+		return -1
+	}
+	if a.id > b.id {
+		return 1
+	}
+	return 0
+}
+
+// page sorts items, skips everything up to cursor and cuts limit+1 items,
+// so that caller can tell whether there is a next page.
+func page(items []*keyed, cursor *paging.Cursor, desc bool, limit int) ([]*keyed, error) {
+	sort.Slice(items, func(i, j int) bool {
+		c := compareKeyed(items[i], items[j])
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	if cursor != nil {
+		value, err := paging.SortValue(cursor)
+		if err != nil {
+			return nil, err
+		}
+		after := &keyed{key: value, id: cursor.ID}
+		start := len(items)
+		for i, item := range items {
+			c := compareKeyed(item, after)
+			if (!desc && c > 0) || (desc && c < 0) {
+				start = i
+				break
+			}
+		}
+		items = items[start:]
+	}
+
+	if len(items) > limit+1 {
+		items = items[:limit+1]
+	}
+	return items, nil
+}
+
+func productKey(item *types.Product, sort string) interface{} {
+	switch sort {
+	case types.SortName:
+		return item.Name
+	case types.SortPrice:
+		return item.Price.Amount
+	case types.SortCreated:
+		return item.Created
+	}
+	return nil
+}
+
+func customerKey(item *types.Customer, sort string) interface{} {
+	switch sort {
+	case types.SortName:
+		return item.Name
+	case types.SortCreated:
+		return item.Created
+	}
+	return nil
+}
+
+func validSort(sort string, allowed ...string) bool {
+	if sort == "" || sort == types.SortID {
+		return true
+	}
+	for _, item := range allowed {
+		if item == sort {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(value, substr string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(substr))
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/types"
+	"sort"
+	"time"
+)
+
+type PriceRepository struct {
+	db *DB
+}
+
+func NewPriceRepository(db *DB) *PriceRepository {
+	return &PriceRepository{db: db}
+}
+
+func copyPrice(price *types.ProductPrice) *types.ProductPrice {
+	result := *price
+	if price.EffectiveTo != nil {
+		end := *price.EffectiveTo
+		result.EffectiveTo = &end
+	}
+	return &result
+}
+
+func (r *PriceRepository) Schedule(ctx context.Context, price *types.ProductPrice) (*types.ProductPrice, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.products[price.ProductID]; !ok {
+		return nil, types.ErrNotFound
+	}
+
+	item := copyPrice(price)
+	item.ID = r.db.nextID()
+	item.Created = now()
+	r.db.prices = append(r.db.prices, item)
+	return copyPrice(item), nil
+}
+
+func (r *PriceRepository) ByProduct(ctx context.Context, productID int64) ([]*types.ProductPrice, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	if _, ok := r.db.products[productID]; !ok {
+		return nil, types.ErrNotFound
+	}
+
+	items := make([]*types.ProductPrice, 0)
+	for _, price := range r.db.prices {
+		if price.ProductID == productID {
+			items = append(items, copyPrice(price))
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].EffectiveFrom.Before(items[j].EffectiveFrom) })
+	return items, nil
+}
+
+func (r *PriceRepository) At(ctx context.Context, productID int64, at time.Time) (*types.ProductPrice, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	price := r.db.priceAt(productID, at)
+	if price == nil {
+		return nil, types.ErrNotFound
+	}
+	return copyPrice(price), nil
+}
+
+func (r *PriceRepository) Cancel(ctx context.Context, productID, id int64) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i, price := range r.db.prices {
+		if price.ID != id || price.ProductID != productID {
+			continue
+		}
+		if !price.EffectiveFrom.After(now()) {
+			return types.ErrPriceInEffect
+		}
+		r.db.prices = append(r.db.prices[:i], r.db.prices[i+1:]...)
+		return nil
+	}
+	return types.ErrNotFound
+}
@@ -0,0 +1,260 @@
+package memory
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/paging"
+	"github.com/bdaler/crud/pkg/types"
+	"github.com/bdaler/crud/pkg/utils"
+	"sort"
+	"strings"
+)
+
+type ProductRepository struct {
+	db *DB
+}
+
+func NewProductRepository(db *DB) *ProductRepository {
+	return &ProductRepository{db: db}
+}
+
+func (r *ProductRepository) Save(ctx context.Context, product *types.Product, managerID int64) (*types.Product, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if product.ID == 0 {
+		item := &types.Product{
+			ID:           r.db.nextID(),
+			Name:         product.Name,
+			Price:        product.Price,
+			Qty:          product.Qty,
+			ReorderLevel: product.ReorderLevel,
+			Active:       true,
+			Created:      now(),
+		}
+		r.db.products[item.ID] = item
+		r.db.changePrice(item.ID, item.Price, managerID)
+		if item.Qty != 0 {
+			r.db.recordMovement(&types.StockMovement{
+				ProductID: item.ID,
+				Kind:      types.MovementReceipt,
+				Qty:       item.Qty,
+				ManagerID: managerID,
+			})
+		}
+		return r.db.copyProduct(item), nil
+	}
+
+	item, ok := r.db.products[product.ID]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	price := product.Price
+	if price.Currency == "" {
+		price.Currency = item.Price.Currency
+	} else if price.Currency != item.Price.Currency {
+		return nil, types.ErrCurrencyMismatch
+	}
+	if delta := product.Qty - item.Qty; delta != 0 {
+		r.db.recordMovement(&types.StockMovement{
+			ProductID: item.ID,
+			Kind:      types.MovementAdjustment,
+			Qty:       delta,
+			ManagerID: managerID,
+		})
+	}
+	// item.Price keeps the last regular price, promotions don't touch it
+	if price != item.Price {
+		item.Price = price
+		r.db.changePrice(item.ID, item.Price, managerID)
+	}
+	item.Name = product.Name
+	item.Qty = product.Qty
+	item.ReorderLevel = product.ReorderLevel
+	return r.db.copyProduct(item), nil
+}
+
+func (r *ProductRepository) List(ctx context.Context, filter *types.ProductFilter) (*types.ProductPage, error) {
+	if !validSort(filter.Sort, types.SortName, types.SortPrice, types.SortCreated) {
+		return nil, types.ErrInvalidSort
+	}
+	cursor, err := paging.Decode(filter.After, filter.Sort)
+	if err != nil {
+		return nil, err
+	}
+	limit := paging.Limit(filter.Limit)
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var subtree map[int64]bool
+	if filter.CategoryID != 0 {
+		subtree = r.db.categorySubtree(filter.CategoryID)
+	}
+
+	items := make([]*keyed, 0)
+	for _, stored := range r.db.products {
+		if !filter.WithInactive && !stored.Active {
+			continue
+		}
+		item := r.db.copyProduct(stored)
+		if filter.MinPrice > 0 && item.Price.Amount < int64(filter.MinPrice) {
+			continue
+		}
+		if filter.MaxPrice > 0 && item.Price.Amount > int64(filter.MaxPrice) {
+			continue
+		}
+		if filter.InStock && item.Available <= 0 {
+			continue
+		}
+		if filter.Name != "" && !containsFold(item.Name, filter.Name) {
+			continue
+		}
+		if subtree != nil && !r.db.inCategories(item.ID, subtree) {
+			continue
+		}
+		items = append(items, &keyed{key: productKey(item, filter.Sort), id: item.ID, item: item})
+	}
+
+	items, err = page(items, cursor, filter.Desc, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.ProductPage{Items: make([]*types.Product, 0, len(items))}
+	for _, item := range items {
+		result.Items = append(result.Items, item.item.(*types.Product))
+	}
+	if len(result.Items) > limit {
+		result.Items = result.Items[:limit]
+		result.NextCursor = paging.Encode(paging.ProductCursor(result.Items[limit-1], filter.Sort))
+	}
+
+	return result, nil
+}
+
+func (r *ProductRepository) ByID(ctx context.Context, id int64) (*types.Product, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	item, ok := r.db.products[id]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	return r.db.copyProduct(item), nil
+}
+
+func (r *ProductRepository) ChangeActive(ctx context.Context, id int64, active bool) (*types.Product, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	item, ok := r.db.products[id]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	item.Active = active
+	return r.db.copyProduct(item), nil
+}
+
+// Search mimics postgres implementation: prefix match of all words or similar
+// enough query, ranked by similarity with a bonus for prefix match.
+func (r *ProductRepository) Search(ctx context.Context, search *types.ProductSearch) ([]*types.ProductHit, error) {
+	words := utils.SearchWords(search.Query)
+	if len(words) == 0 {
+		return []*types.ProductHit{}, nil
+	}
+	query := strings.Join(words, " ")
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	items := make([]*types.ProductHit, 0)
+	for _, item := range r.db.products {
+		if !item.Active {
+			continue
+		}
+		matched := prefixMatch(words, item.Name)
+		rank := wordSimilarity(query, item.Name)
+		if !matched && rank < wordSimilarityThreshold {
+			continue
+		}
+		if matched {
+			rank += 0.1
+		}
+		items = append(items, &types.ProductHit{
+			Product:   r.db.copyProduct(item),
+			Rank:      rank,
+			Highlight: highlight(words, item.Name),
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Rank == items[j].Rank {
+			return items[i].ID < items[j].ID
+		}
+		return items[i].Rank > items[j].Rank
+	})
+	if limit := paging.Limit(search.Limit); len(items) > limit {
+		items = items[:limit]
+	}
+
+	return items, nil
+}
+
+func (r *ProductRepository) LowStock(ctx context.Context) ([]*types.Product, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	items := make([]*types.Product, 0)
+	for _, item := range r.db.products {
+		if item.Active && item.LowStock() {
+			items = append(items, r.db.copyProduct(item))
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items, nil
+}
+
+func (r *ProductRepository) Purge(ctx context.Context, id int64) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.products[id]; !ok {
+		return types.ErrNotFound
+	}
+	for _, sale := range r.db.sales {
+		for _, position := range sale.Positions {
+			if position.ProductID == id {
+				return types.ErrProductInUse
+			}
+		}
+	}
+
+	delete(r.db.products, id)
+	delete(r.db.productCategories, id)
+	for _, items := range r.db.carts {
+		delete(items, id)
+	}
+	movements := r.db.movements[:0]
+	for _, movement := range r.db.movements {
+		if movement.ProductID != id {
+			movements = append(movements, movement)
+		}
+	}
+	r.db.movements = movements
+	prices := r.db.prices[:0]
+	for _, price := range r.db.prices {
+		if price.ProductID != id {
+			prices = append(prices, price)
+		}
+	}
+	r.db.prices = prices
+	for _, reservation := range r.db.reserved {
+		positions := reservation.Positions[:0]
+		for _, position := range reservation.Positions {
+			if position.ProductID != id {
+				positions = append(positions, position)
+			}
+		}
+		reservation.Positions = positions
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/types"
+	"sort"
+)
+
+type PromoRepository struct {
+	db *DB
+}
+
+func NewPromoRepository(db *DB) *PromoRepository {
+	return &PromoRepository{db: db}
+}
+
+// promoUses must be called with mu locked, zero customerID counts all sales.
+func (db *DB) promoUses(promoID, customerID int64) int {
+	uses := 0
+	for _, sale := range db.sales {
+		if sale.PromoCodeID == promoID && (customerID == 0 || sale.CustomerID == customerID) {
+			uses++
+		}
+	}
+	return uses
+}
+
+// copyPromo must be called with mu locked, it fills uses.
+func (db *DB) copyPromo(promo *types.PromoCode) *types.PromoCode {
+	result := *promo
+	if promo.ValidTo != nil {
+		end := *promo.ValidTo
+		result.ValidTo = &end
+	}
+	result.Uses = db.promoUses(promo.ID, 0)
+	return &result
+}
+
+// applyPromo must be called with mu locked, after prices of positions are set.
+func (db *DB) applyPromo(sale *types.Sale) error {
+	for _, promo := range db.promos {
+		if promo.Code != sale.PromoCode {
+			continue
+		}
+		customerUses := 0
+		if sale.CustomerID != 0 {
+			customerUses = db.promoUses(promo.ID, sale.CustomerID)
+		}
+		return db.copyPromo(promo).Apply(sale, customerUses, now())
+	}
+	return types.ErrPromoInvalid
+}
+
+func (r *PromoRepository) Save(ctx context.Context, promo *types.PromoCode) (*types.PromoCode, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, item := range r.db.promos {
+		if item.Code == promo.Code && item.ID != promo.ID {
+			return nil, types.ErrPromoCodeUsed
+		}
+	}
+	if _, ok := r.db.products[promo.ProductID]; promo.ProductID != 0 && !ok {
+		return nil, types.ErrNotFound
+	}
+
+	item := r.db.copyPromo(promo)
+	item.Uses = 0
+	if promo.ID == 0 {
+		item.ID = r.db.nextID()
+		item.Created = now()
+	} else {
+		stored, ok := r.db.promos[promo.ID]
+		if !ok {
+			return nil, types.ErrNotFound
+		}
+		item.Created = stored.Created
+	}
+	r.db.promos[item.ID] = item
+	return r.db.copyPromo(item), nil
+}
+
+func (r *PromoRepository) ByID(ctx context.Context, id int64) (*types.PromoCode, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	promo, ok := r.db.promos[id]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	return r.db.copyPromo(promo), nil
+}
+
+func (r *PromoRepository) All(ctx context.Context) ([]*types.PromoCode, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	items := make([]*types.PromoCode, 0, len(r.db.promos))
+	for _, promo := range r.db.promos {
+		items = append(items, r.db.copyPromo(promo))
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID > items[j].ID })
+	return items, nil
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/types"
+	"time"
+)
+
+type ReservationRepository struct {
+	db *DB
+}
+
+func NewReservationRepository(db *DB) *ReservationRepository {
+	return &ReservationRepository{db: db}
+}
+
+func copyReservation(item *types.Reservation) *types.Reservation {
+	result := *item
+	result.Positions = make([]*types.ReservationPosition, 0, len(item.Positions))
+	for _, position := range item.Positions {
+		copied := *position
+		result.Positions = append(result.Positions, &copied)
+	}
+	return &result
+}
+
+func (r *ReservationRepository) Create(ctx context.Context, reservation *types.Reservation, ttl time.Duration) (*types.Reservation, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	requested := make(map[int64]int)
+	for _, position := range reservation.Positions {
+		product, ok := r.db.products[position.ProductID]
+		if !ok {
+			return nil, types.ErrNotFound
+		}
+		if !product.Active {
+			return nil, types.ErrInvalidPosition
+		}
+		requested[position.ProductID] += position.Qty
+		if product.Qty-r.db.reservedQty(product.ID, 0) < requested[position.ProductID] {
+			return nil, types.ErrNotEnoughQty
+		}
+	}
+
+	item := copyReservation(reservation)
+	item.ID = r.db.nextID()
+	item.Created = now()
+	item.Expire = item.Created.Add(ttl)
+	for _, position := range item.Positions {
+		position.ID = r.db.nextID()
+		position.ReservationID = item.ID
+	}
+	r.db.reserved[item.ID] = item
+
+	return copyReservation(item), nil
+}
+
+func (r *ReservationRepository) ByID(ctx context.Context, id int64) (*types.Reservation, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	item, ok := r.db.reserved[id]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	return copyReservation(item), nil
+}
+
+func (r *ReservationRepository) Delete(ctx context.Context, id int64) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.reserved[id]; !ok {
+		return types.ErrNotFound
+	}
+	delete(r.db.reserved, id)
+	return nil
+}
+
+func (r *ReservationRepository) Confirm(ctx context.Context, id, managerID int64) (*types.Sale, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	reservation, ok := r.db.reserved[id]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	if !reservation.Expire.After(now()) {
+		return nil, types.ErrReservationExpired
+	}
+
+	if managerID == 0 {
+		managerID = reservation.ManagerID
+	}
+	sale := &types.Sale{
+		ManagerID:  managerID,
+		CustomerID: reservation.CustomerID,
+		Positions:  make([]*types.SalePosition, 0, len(reservation.Positions)),
+	}
+	for _, position := range reservation.Positions {
+		sale.Positions = append(sale.Positions, &types.SalePosition{
+			ProductID: position.ProductID,
+			Qty:       position.Qty,
+		})
+	}
+
+	// held quantity must not count against the sale made from it
+	delete(r.db.reserved, id)
+	if err := r.db.createSale(sale); err != nil {
+		r.db.reserved[id] = reservation
+		return nil, err
+	}
+
+	return sale, nil
+}
+
+func (r *ReservationRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	count := int64(0)
+	current := now()
+	for id, reservation := range r.db.reserved {
+		if !reservation.Expire.After(current) {
+			delete(r.db.reserved, id)
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/types"
+	"sort"
+)
+
+type ReturnRepository struct {
+	db *DB
+}
+
+func NewReturnRepository(db *DB) *ReturnRepository {
+	return &ReturnRepository{db: db}
+}
+
+func copyReturn(ret *types.Return) *types.Return {
+	result := *ret
+	result.Positions = make([]*types.ReturnPosition, 0, len(ret.Positions))
+	for _, position := range ret.Positions {
+		copied := *position
+		result.Positions = append(result.Positions, &copied)
+	}
+	// totals were calculated on create, can't fail
+	_ = result.CalcTotals()
+	return &result
+}
+
+func (r *ReturnRepository) Create(ctx context.Context, ret *types.Return) (*types.Return, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	sale, ok := r.db.sales[ret.SaleID]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	if sale.Voided != nil {
+		return nil, types.ErrSaleVoided
+	}
+
+	left := make(map[int64]int, len(sale.Positions))
+	sold := make(map[int64]*types.SalePosition, len(sale.Positions))
+	for _, position := range sale.Positions {
+		left[position.ID] = position.Qty - r.db.returnedQty(position.ID)
+		sold[position.ID] = position
+	}
+
+	if len(ret.Positions) == 0 {
+		for _, position := range sale.Positions {
+			if left[position.ID] > 0 {
+				ret.Positions = append(ret.Positions, &types.ReturnPosition{SalePositionID: position.ID, Qty: left[position.ID]})
+			}
+		}
+		if len(ret.Positions) == 0 {
+			return nil, types.ErrReturnExceedsQty
+		}
+	}
+
+	for _, position := range ret.Positions {
+		item, ok := sold[position.SalePositionID]
+		if !ok {
+			return nil, types.ErrInvalidPosition
+		}
+		if position.Qty > left[item.ID] {
+			return nil, types.ErrReturnExceedsQty
+		}
+		left[item.ID] -= position.Qty
+		position.ProductID = item.ProductID
+		price, err := item.PaidPrice()
+		if err != nil {
+			return nil, err
+		}
+		position.Price = price
+	}
+	if err := ret.CalcTotals(); err != nil {
+		return nil, err
+	}
+
+	ret.ID = r.db.nextID()
+	ret.Created = now()
+	for _, position := range ret.Positions {
+		position.ID = r.db.nextID()
+		position.ReturnID = ret.ID
+		position.Created = ret.Created
+		if product, ok := r.db.products[position.ProductID]; ok {
+			product.Qty += position.Qty
+			r.db.recordMovement(&types.StockMovement{
+				ProductID: position.ProductID,
+				Kind:      types.MovementReturn,
+				Qty:       position.Qty,
+				ManagerID: ret.ManagerID,
+				SaleID:    ret.SaleID,
+				ReturnID:  ret.ID,
+				Reason:    ret.Reason,
+			})
+		}
+	}
+	r.db.returns[ret.ID] = copyReturn(ret)
+	return ret, nil
+}
+
+func (r *ReturnRepository) BySale(ctx context.Context, saleID int64) ([]*types.Return, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	items := make([]*types.Return, 0)
+	for _, ret := range r.db.returns {
+		if ret.SaleID == saleID {
+			items = append(items, copyReturn(ret))
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items, nil
+}
@@ -0,0 +1,202 @@
+package memory
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/paging"
+	"github.com/bdaler/crud/pkg/types"
+	"sort"
+)
+
+type SaleRepository struct {
+	db *DB
+}
+
+func NewSaleRepository(db *DB) *SaleRepository {
+	return &SaleRepository{db: db}
+}
+
+func (r *SaleRepository) Create(ctx context.Context, sale *types.Sale) (*types.Sale, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if err := r.db.createSale(sale); err != nil {
+		return nil, err
+	}
+	for _, position := range sale.Positions {
+		r.db.useReserved(sale.CustomerID, position.ProductID, position.Qty)
+	}
+	return sale, nil
+}
+
+func (r *SaleRepository) TotalByManager(ctx context.Context, managerID int64) ([]types.Money, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	totals := make([]types.Money, 0, 1)
+	for _, stored := range r.db.sales {
+		if stored.ManagerID != managerID || stored.Voided != nil {
+			continue
+		}
+		sale := r.copySale(stored)
+		net, err := sale.Total.Sub(sale.Returned)
+		if err == nil {
+			totals, err = types.AddByCurrency(totals, net)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].Currency < totals[j].Currency })
+	return totals, nil
+}
+
+// copySale must be called with mu locked, it resolves product names and customer.
+func (r *SaleRepository) copySale(sale *types.Sale) *types.Sale {
+	result := *sale
+	result.Positions = make([]*types.SalePosition, 0, len(sale.Positions))
+	for _, position := range sale.Positions {
+		copied := *position
+		if product, ok := r.db.products[position.ProductID]; ok {
+			copied.ProductName = product.Name
+		}
+		copied.ReturnedQty = r.db.returnedQty(position.ID)
+		result.Positions = append(result.Positions, &copied)
+	}
+	result.Payments = make([]*types.Payment, 0, len(sale.Payments))
+	for _, payment := range sale.Payments {
+		copied := *payment
+		result.Payments = append(result.Payments, &copied)
+	}
+	if sale.Voided != nil {
+		voided := *sale.Voided
+		result.Voided = &voided
+	}
+	if customer, ok := r.db.customers[sale.CustomerID]; ok {
+		result.Customer = copyCustomer(customer)
+	}
+	// positions were checked by createSale, totals can't fail
+	_ = result.CalcTotals()
+	return &result
+}
+
+func (r *SaleRepository) AssignManager(ctx context.Context, saleID, managerID int64) (*types.Sale, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	sale, ok := r.db.sales[saleID]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	if sale.ManagerID != 0 {
+		return nil, types.ErrSaleAssigned
+	}
+	sale.ManagerID = managerID
+	return r.copySale(sale), nil
+}
+
+func (r *SaleRepository) Pay(ctx context.Context, saleID int64, payments []*types.Payment) (*types.Sale, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	stored, ok := r.db.sales[saleID]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	if err := r.copySale(stored).Pay(payments); err != nil {
+		return nil, err
+	}
+	r.db.addPayments(stored, payments)
+	return r.copySale(stored), nil
+}
+
+func (r *SaleRepository) Void(ctx context.Context, saleID, managerID int64) (*types.Sale, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	stored, ok := r.db.sales[saleID]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	if stored.Voided != nil {
+		return nil, types.ErrSaleVoided
+	}
+	if len(stored.Payments) != 0 {
+		return nil, types.ErrSaleHasPayments
+	}
+
+	// returned quantity is back in stock already
+	for _, position := range stored.Positions {
+		qty := position.Qty - r.db.returnedQty(position.ID)
+		product, ok := r.db.products[position.ProductID]
+		if qty <= 0 || !ok {
+			continue
+		}
+		product.Qty += qty
+		r.db.recordMovement(&types.StockMovement{
+			ProductID:  position.ProductID,
+			Kind:       types.MovementVoid,
+			Qty:        qty,
+			ManagerID:  managerID,
+			CustomerID: stored.CustomerID,
+			SaleID:     stored.ID,
+		})
+	}
+	voided := now()
+	stored.Voided = &voided
+	return r.copySale(stored), nil
+}
+
+func (r *SaleRepository) ByID(ctx context.Context, id int64) (*types.Sale, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	sale, ok := r.db.sales[id]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	return r.copySale(sale), nil
+}
+
+func (r *SaleRepository) List(ctx context.Context, filter *types.SaleFilter) (*types.SalePage, error) {
+	cursor, err := paging.Decode(filter.After, types.SortCreated)
+	if err != nil {
+		return nil, err
+	}
+	limit := paging.Limit(filter.Limit)
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	items := make([]*keyed, 0)
+	for _, sale := range r.db.sales {
+		if !filter.From.IsZero() && sale.Created.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !sale.Created.Before(filter.To) {
+			continue
+		}
+		if filter.CustomerID != 0 && sale.CustomerID != filter.CustomerID {
+			continue
+		}
+		if filter.ManagerID != 0 && sale.ManagerID != filter.ManagerID {
+			continue
+		}
+		items = append(items, &keyed{key: sale.Created, id: sale.ID, item: sale})
+	}
+
+	items, err = page(items, cursor, true, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.SalePage{Items: make([]*types.Sale, 0, len(items))}
+	for _, item := range items {
+		result.Items = append(result.Items, r.copySale(item.item.(*types.Sale)))
+	}
+	if len(result.Items) > limit {
+		result.Items = result.Items[:limit]
+		result.NextCursor = paging.Encode(paging.SaleCursor(result.Items[limit-1]))
+	}
+
+	return result, nil
+}
@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"github.com/bdaler/crud/pkg/utils"
+	"strings"
+	"unicode"
+)
+
+// wordSimilarityThreshold is the default pg_trgm.word_similarity_threshold.
+const wordSimilarityThreshold = 0.6
+
+// trigrams of words padded the same way pg_trgm does it, in text order.
+func trigrams(text string) []string {
+	result := make([]string, 0)
+	for _, word := range utils.SearchWords(text) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			result = append(result, string(padded[i:i+3]))
+		}
+	}
+	return result
+}
+
+// wordSimilarity mirrors pg_trgm word_similarity: the greatest similarity
+// between trigrams of query and any continuous extent of trigrams of text.
+func wordSimilarity(query, text string) float64 {
+	set := make(map[string]bool)
+	for _, trigram := range trigrams(query) {
+		set[trigram] = true
+	}
+	sequence := trigrams(text)
+	if len(set) == 0 || len(sequence) == 0 {
+		return 0
+	}
+
+	best := 0.0
+	for i := range sequence {
+		extent := make(map[string]bool)
+		common := 0
+		for j := i; j < len(sequence); j++ {
+			if extent[sequence[j]] {
+				continue
+			}
+			extent[sequence[j]] = true
+			if set[sequence[j]] {
+				common++
+			}
+			value := float64(common) / float64(len(set)+len(extent)-common)
+			if value > best {
+				best = value
+			}
+		}
+	}
+	return best
+}
+
+// prefixMatch reports whether every query word starts some word of text.
+func prefixMatch(words []string, text string) bool {
+	textWords := utils.SearchWords(text)
+	for _, word := range words {
+		found := false
+		for _, textWord := range textWords {
+			if strings.HasPrefix(textWord, word) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// highlight wraps words of text starting with any of query words into <b></b>
+// keeping everything else as is.
+func highlight(words []string, text string) string {
+	var result, word strings.Builder
+	flush := func() {
+		value := word.String()
+		word.Reset()
+		if value == "" {
+			return
+		}
+		for _, prefix := range words {
+			if strings.HasPrefix(strings.ToLower(value), prefix) {
+				result.WriteString("<b>" + value + "</b>")
+				return
+			}
+		}
+		result.WriteString(value)
+	}
+
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word.WriteRune(r)
+			continue
+		}
+		flush()
+		result.WriteRune(r)
+	}
+	flush()
+	return result.String()
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/paging"
+	"github.com/bdaler/crud/pkg/types"
+	"sort"
+)
+
+type StockRepository struct {
+	db *DB
+}
+
+func NewStockRepository(db *DB) *StockRepository {
+	return &StockRepository{db: db}
+}
+
+func (r *StockRepository) Move(ctx context.Context, movement *types.StockMovement) (*types.StockMovement, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	product, ok := r.db.products[movement.ProductID]
+	if !ok {
+		return nil, types.ErrNotFound
+	}
+	if product.Qty+movement.Qty < 0 {
+		return nil, types.ErrNotEnoughQty
+	}
+
+	product.Qty += movement.Qty
+	r.db.recordMovement(movement)
+	return movement, nil
+}
+
+func (r *StockRepository) Movements(ctx context.Context, filter *types.MovementFilter) (*types.MovementPage, error) {
+	cursor, err := paging.Decode(filter.After, types.SortID)
+	if err != nil {
+		return nil, err
+	}
+	limit := paging.Limit(filter.Limit)
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	page := &types.MovementPage{Items: make([]*types.StockMovement, 0)}
+	for i := len(r.db.movements) - 1; i >= 0; i-- {
+		movement := r.db.movements[i]
+		if movement.ProductID != filter.ProductID {
+			continue
+		}
+		if cursor != nil && movement.ID >= cursor.ID {
+			continue
+		}
+		if len(page.Items) == limit {
+			page.NextCursor = paging.Encode(paging.MovementCursor(page.Items[limit-1]))
+			break
+		}
+		copied := *movement
+		page.Items = append(page.Items, &copied)
+	}
+
+	return page, nil
+}
+
+func (r *StockRepository) Mismatches(ctx context.Context) ([]*types.StockMismatch, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	ledger := make(map[int64]int)
+	for _, movement := range r.db.movements {
+		ledger[movement.ProductID] += movement.Qty
+	}
+
+	items := make([]*types.StockMismatch, 0)
+	for _, product := range r.db.products {
+		if product.Qty != ledger[product.ID] {
+			items = append(items, &types.StockMismatch{
+				ProductID: product.ID,
+				Name:      product.Name,
+				Qty:       product.Qty,
+				LedgerQty: ledger[product.ID],
+			})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ProductID < items[j].ProductID })
+	return items, nil
+}
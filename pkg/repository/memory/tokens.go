@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/repository"
+	"github.com/bdaler/crud/pkg/types"
+	"time"
+)
+
+type TokenRepository struct {
+	db *DB
+}
+
+func NewTokenRepository(db *DB) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+func (r *TokenRepository) Save(ctx context.Context, principal repository.Principal, value string, id int64, ttl time.Duration) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.tokens[principal][value] = &token{id: id, expire: now().Add(ttl)}
+	return nil
+}
+
+func (r *TokenRepository) Find(ctx context.Context, principal repository.Principal, value string) (int64, bool, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	t, ok := r.db.tokens[principal][value]
+	if !ok {
+		return 0, false, types.ErrTokenNotFound
+	}
+	return t.id, t.expire.Before(now()), nil
+}
+
+func (r *TokenRepository) Prolong(ctx context.Context, principal repository.Principal, value string, ttl time.Duration) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if t, ok := r.db.tokens[principal][value]; ok {
+		t.expire = now().Add(ttl)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/bdaler/crud/pkg/types"
+	"github.com/bdaler/crud/pkg/utils"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type CartRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewCartRepository(pool *pgxpool.Pool) *CartRepository {
+	return &CartRepository{pool: pool}
+}
+
+func (r *CartRepository) ByCustomer(ctx context.Context, customerID int64) (*types.Cart, error) {
+	sqlStatement := `SELECT ci.product_id, p.name, product_price(p.id, ` + utcNow + `), p.currency, ci.qty, ci.created
+	FROM carts_items ci
+	JOIN products p ON p.id = ci.product_id
+	WHERE ci.customer_id = $1
+	ORDER BY ci.created, ci.product_id`
+	rows, err := r.pool.Query(ctx, sqlStatement, customerID)
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	defer rows.Close()
+
+	cart := &types.Cart{CustomerID: customerID, Items: make([]*types.CartItem, 0)}
+	for rows.Next() {
+		item := &types.CartItem{}
+		err = rows.Scan(&item.ProductID, &item.ProductName, &item.Price.Amount, &item.Price.Currency, &item.Qty, &item.Created)
+		if err != nil {
+			logger.Error(err)
+			return nil, types.ErrInternal
+		}
+		cart.Items = append(cart.Items, item)
+	}
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return nil, types.ErrInternal
+	}
+
+	if err = cart.CalcTotals(); err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	return cart, nil
+}
+
+func (r *CartRepository) SetItem(ctx context.Context, customerID, productID int64, qty int) error {
+	sqlStatement := `INSERT INTO carts_items(customer_id, product_id, qty) VALUES ($1, $2, $3)
+	ON CONFLICT (customer_id, product_id) DO UPDATE SET qty = excluded.qty`
+	_, err := r.pool.Exec(ctx, sqlStatement, customerID, productID, qty)
+	if utils.IsForeignKeyViolation(err) {
+		return types.ErrNotFound
+	}
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+	return nil
+}
+
+func (r *CartRepository) RemoveItem(ctx context.Context, customerID, productID int64) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM carts_items WHERE customer_id = $1 AND product_id = $2`, customerID, productID)
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+	if tag.RowsAffected() == 0 {
+		return types.ErrNotFound
+	}
+	return nil
+}
+
+func (r *CartRepository) Clear(ctx context.Context, customerID int64) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM carts_items WHERE customer_id = $1`, customerID)
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+	return nil
+}
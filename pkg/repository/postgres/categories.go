@@ -0,0 +1,204 @@
+package postgres
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/bdaler/crud/pkg/types"
+	"github.com/bdaler/crud/pkg/utils"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const categoryColumns = `id, COALESCE(parent_id, 0), name, tax_rate, tax_inclusive, created`
+
+// categorySubtree selects id of the category given as $n and ids of all its descendants.
+const categorySubtree = `WITH RECURSIVE tree AS (
+		SELECT id FROM categories WHERE id = %s
+		UNION ALL
+		SELECT c.id FROM categories c JOIN tree t ON c.parent_id = t.id
+	) SELECT id FROM tree`
+
+type CategoryRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewCategoryRepository(pool *pgxpool.Pool) *CategoryRepository {
+	return &CategoryRepository{pool: pool}
+}
+
+func scanCategory(row rowScanner) (*types.Category, error) {
+	item := &types.Category{}
+	err := row.Scan(&item.ID, &item.ParentID, &item.Name, &item.TaxRate, &item.TaxInclusive, &item.Created)
+	if err == pgx.ErrNoRows {
+		return nil, types.ErrNotFound
+	}
+	// unknown parent
+	if utils.IsForeignKeyViolation(err) {
+		return nil, types.ErrNotFound
+	}
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	return item, nil
+}
+
+func (r *CategoryRepository) Save(ctx context.Context, category *types.Category) (*types.Category, error) {
+	var result *types.Category
+	err := inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		if category.ParentID != 0 {
+			// ancestors of the new parent must not include the category itself
+			cycle := false
+			err := tx.QueryRow(ctx, `WITH RECURSIVE up AS (
+				SELECT id, parent_id FROM categories WHERE id = $1
+				UNION ALL
+				SELECT c.id, c.parent_id FROM categories c JOIN up ON c.id = up.parent_id
+			) SELECT EXISTS(SELECT 1 FROM up WHERE id = $2)`, category.ParentID, category.ID).Scan(&cycle)
+			if err != nil {
+				logger.Error(err)
+				return types.ErrInternal
+			}
+			if cycle {
+				return types.ErrCategoryCycle
+			}
+		}
+
+		var err error
+		if category.ID == 0 {
+			sql := `INSERT INTO categories(parent_id, name, tax_rate, tax_inclusive) VALUES (NULLIF($1, 0), $2, $3, $4)
+			RETURNING ` + categoryColumns
+			result, err = scanCategory(tx.QueryRow(ctx, sql, category.ParentID, category.Name, category.TaxRate, category.TaxInclusive))
+		} else {
+			sql := `UPDATE categories SET parent_id = NULLIF($2, 0), name = $3, tax_rate = $4, tax_inclusive = $5
+			WHERE id = $1 RETURNING ` + categoryColumns
+			result, err = scanCategory(tx.QueryRow(ctx, sql,
+				category.ID, category.ParentID, category.Name, category.TaxRate, category.TaxInclusive))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *CategoryRepository) ByID(ctx context.Context, id int64) (*types.Category, error) {
+	return scanCategory(r.pool.QueryRow(ctx, `SELECT `+categoryColumns+` FROM categories WHERE id = $1`, id))
+}
+
+func (r *CategoryRepository) All(ctx context.Context) ([]*types.Category, error) {
+	return r.queryCategories(ctx, `SELECT `+categoryColumns+` FROM categories ORDER BY name, id`)
+}
+
+func (r *CategoryRepository) Delete(ctx context.Context, id int64) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM categories WHERE id = $1`, id)
+	if utils.IsForeignKeyViolation(err) {
+		return types.ErrCategoryNotEmpty
+	}
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+	if tag.RowsAffected() == 0 {
+		return types.ErrNotFound
+	}
+	return nil
+}
+
+func (r *CategoryRepository) ByProduct(ctx context.Context, productID int64) ([]*types.Category, error) {
+	return r.queryCategories(ctx, `SELECT c.id, COALESCE(c.parent_id, 0), c.name, c.tax_rate, c.tax_inclusive, c.created
+	FROM categories c
+	JOIN products_categories pc ON pc.category_id = c.id
+	WHERE pc.product_id = $1
+	ORDER BY c.name, c.id`, productID)
+}
+
+func (r *CategoryRepository) SetProductCategories(ctx context.Context, productID int64, categoryIDs []int64) error {
+	err := inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM products_categories WHERE product_id = $1`, productID); err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+
+		for _, categoryID := range categoryIDs {
+			_, err := tx.Exec(ctx, `INSERT INTO products_categories(product_id, category_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING`, productID, categoryID)
+			if utils.IsForeignKeyViolation(err) {
+				return types.ErrNotFound
+			}
+			if err != nil {
+				logger.Error(err)
+				return types.ErrInternal
+			}
+		}
+
+		return nil
+	})
+	return err
+}
+
+// taxRules must be called in the transaction which creates the sale, the rate of
+// a product is the nearest one up the tree of each of its categories, the highest
+// of them when categories disagree.
+func taxRules(ctx context.Context, tx pgx.Tx, productIDs []int64) (map[int64]types.TaxRule, error) {
+	rows, err := tx.Query(ctx, `WITH RECURSIVE up AS (
+		SELECT pc.product_id, c.parent_id, c.tax_rate, c.tax_inclusive
+		FROM products_categories pc
+		JOIN categories c ON c.id = pc.category_id
+		WHERE pc.product_id = ANY($1)
+		UNION ALL
+		SELECT up.product_id, c.parent_id, c.tax_rate, c.tax_inclusive
+		FROM up
+		JOIN categories c ON c.id = up.parent_id
+		WHERE up.tax_rate IS NULL
+	) SELECT product_id, tax_rate, tax_inclusive FROM up WHERE tax_rate IS NOT NULL`, productIDs)
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	defer rows.Close()
+
+	rules := make(map[int64]types.TaxRule)
+	for rows.Next() {
+		var productID int64
+		rule := types.TaxRule{}
+		if err = rows.Scan(&productID, &rule.Rate, &rule.Inclusive); err != nil {
+			logger.Error(err)
+			return nil, types.ErrInternal
+		}
+		if current, ok := rules[productID]; !ok || rule.Rate > current.Rate {
+			rules[productID] = rule
+		}
+	}
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return nil, types.ErrInternal
+	}
+
+	return rules, nil
+}
+
+func (r *CategoryRepository) queryCategories(ctx context.Context, sql string, args ...interface{}) ([]*types.Category, error) {
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	defer rows.Close()
+
+	items := make([]*types.Category, 0)
+	for rows.Next() {
+		item, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return nil, types.ErrInternal
+	}
+
+	return items, nil
+}
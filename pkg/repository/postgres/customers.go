@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/bdaler/crud/pkg/paging"
+	"github.com/bdaler/crud/pkg/types"
+	"github.com/bdaler/crud/pkg/utils"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const customerColumns = `id, name, phone, active, created`
+
+type CustomerRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewCustomerRepository(pool *pgxpool.Pool) *CustomerRepository {
+	return &CustomerRepository{pool: pool}
+}
+
+func scanCustomer(row rowScanner) (*types.Customer, error) {
+	item := &types.Customer{}
+	err := row.Scan(&item.ID, &item.Name, &item.Phone, &item.Active, &item.Created)
+	if err == pgx.ErrNoRows {
+		return nil, types.ErrNotFound
+	}
+	if utils.IsUniqueViolation(err) {
+		return nil, types.ErrPhoneUsed
+	}
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	return item, nil
+}
+
+func (r *CustomerRepository) ByID(ctx context.Context, id int64) (*types.Customer, error) {
+	sqlStatement := `SELECT ` + customerColumns + ` FROM customers WHERE id = $1`
+	return scanCustomer(r.pool.QueryRow(ctx, sqlStatement, id))
+}
+
+func (r *CustomerRepository) All(ctx context.Context) ([]*types.Customer, error) {
+	return r.queryCustomers(ctx, `SELECT `+customerColumns+` FROM customers ORDER BY id`)
+}
+
+func (r *CustomerRepository) AllActive(ctx context.Context) ([]*types.Customer, error) {
+	return r.queryCustomers(ctx, `SELECT `+customerColumns+` FROM customers WHERE active = TRUE ORDER BY id LIMIT 500`)
+}
+
+var customerSorts = map[string]string{
+	"":                "id",
+	types.SortID:      "id",
+	types.SortName:    "name",
+	types.SortCreated: "created",
+}
+
+func (r *CustomerRepository) List(ctx context.Context, filter *types.CustomerFilter) (*types.CustomerPage, error) {
+	column, ok := customerSorts[filter.Sort]
+	if !ok {
+		return nil, types.ErrInvalidSort
+	}
+	cursor, err := paging.Decode(filter.After, filter.Sort)
+	if err != nil {
+		return nil, err
+	}
+	limit := paging.Limit(filter.Limit)
+
+	q := &query{}
+	q.and("active = TRUE")
+	if filter.Name != "" {
+		pattern := q.arg(likePattern(filter.Name))
+		q.and("(name ILIKE " + pattern + " OR phone ILIKE " + pattern + ")")
+	}
+	if cursor != nil {
+		value, err := paging.SortValue(cursor)
+		if err != nil {
+			return nil, err
+		}
+		q.keyset(column, value, cursor.ID, filter.Desc)
+	}
+
+	sqlStatement := `SELECT ` + customerColumns + ` FROM customers` + q.whereSQL() +
+		orderBy(column, filter.Desc) + ` LIMIT ` + q.arg(limit+1)
+	items, err := r.queryCustomers(ctx, sqlStatement, q.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &types.CustomerPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = paging.Encode(paging.CustomerCursor(page.Items[limit-1], filter.Sort))
+	}
+
+	return page, nil
+}
+
+func (r *CustomerRepository) queryCustomers(ctx context.Context, sqlStatement string, args ...interface{}) ([]*types.Customer, error) {
+	items := make([]*types.Customer, 0)
+	rows, err := r.pool.Query(ctx, sqlStatement, args...)
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scanCustomer(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return nil, types.ErrInternal
+	}
+
+	return items, nil
+}
+
+func (r *CustomerRepository) Save(ctx context.Context, customer *types.Customer) (*types.Customer, error) {
+	if customer.ID == 0 {
+		sqlStatement := `INSERT INTO customers(name, phone, password) VALUES ($1, $2, $3) RETURNING ` + customerColumns
+		return scanCustomer(r.pool.QueryRow(ctx, sqlStatement, customer.Name, customer.Phone, customer.Password))
+	}
+
+	sqlStatement := `UPDATE customers SET name = $1, phone = $2, password = $3 WHERE id = $4 RETURNING ` + customerColumns
+	return scanCustomer(r.pool.QueryRow(ctx, sqlStatement, customer.Name, customer.Phone, customer.Password, customer.ID))
+}
+
+func (r *CustomerRepository) Change(ctx context.Context, customer *types.Customer) (*types.Customer, error) {
+	sqlStatement := `UPDATE customers SET name = $2, phone = $3, active = $4 WHERE id = $1 RETURNING ` + customerColumns
+	return scanCustomer(r.pool.QueryRow(ctx, sqlStatement, customer.ID, customer.Name, customer.Phone, customer.Active))
+}
+
+func (r *CustomerRepository) ChangeActive(ctx context.Context, id int64, active bool) (*types.Customer, error) {
+	sqlStatement := `UPDATE customers SET active = $2 WHERE id = $1 RETURNING ` + customerColumns
+	return scanCustomer(r.pool.QueryRow(ctx, sqlStatement, id, active))
+}
+
+func (r *CustomerRepository) Delete(ctx context.Context, id int64) (*types.Customer, error) {
+	sqlStatement := `DELETE FROM customers WHERE id = $1 RETURNING ` + customerColumns
+	return scanCustomer(r.pool.QueryRow(ctx, sqlStatement, id))
+}
+
+func (r *CustomerRepository) Credentials(ctx context.Context, phone string) (int64, string, error) {
+	return credentials(ctx, r.pool, `SELECT id, password FROM customers WHERE phone = $1`, phone)
+}
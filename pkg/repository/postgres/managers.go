@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/bdaler/crud/pkg/types"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type ManagerRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewManagerRepository(pool *pgxpool.Pool) *ManagerRepository {
+	return &ManagerRepository{pool: pool}
+}
+
+func (r *ManagerRepository) Create(ctx context.Context, manager *types.Manager) (id int64, err error) {
+	err = inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		sqlStmt := `INSERT INTO managers(name, phone) VALUES ($1, $2) ON CONFLICT (phone) DO NOTHING RETURNING id`
+		err := tx.QueryRow(ctx, sqlStmt, manager.Name, manager.Phone).Scan(&id)
+		if err == pgx.ErrNoRows {
+			return types.ErrPhoneUsed
+		}
+		if err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+
+		tag, err := tx.Exec(
+			ctx,
+			`INSERT INTO managers_roles(manager_id, role_id) SELECT $1, id FROM roles WHERE name = ANY($2)`,
+			id,
+			manager.Roles)
+		if err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+		if int(tag.RowsAffected()) != len(manager.Roles) {
+			return types.ErrUnknownRole
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *ManagerRepository) Credentials(ctx context.Context, phone string) (int64, string, error) {
+	return credentials(ctx, r.pool, `SELECT id, password FROM managers WHERE phone = $1`, phone)
+}
+
+func (r *ManagerRepository) HasAnyRole(ctx context.Context, id int64, roles ...string) (bool, error) {
+	var has bool
+	sqlStmt := `SELECT EXISTS(
+		SELECT 1 FROM managers_roles mr
+		JOIN roles r ON r.id = mr.role_id
+		WHERE mr.manager_id = $1 AND r.name = ANY($2)
+	)`
+	err := r.pool.QueryRow(ctx, sqlStmt, id, roles).Scan(&has)
+	if err != nil {
+		logger.Error(err)
+		return false, types.ErrInternal
+	}
+
+	return has, nil
+}
+
+func (r *ManagerRepository) ByID(ctx context.Context, id int64) (*types.Manager, error) {
+	item := &types.Manager{}
+	err := r.pool.QueryRow(ctx, `SELECT id, name, salary, plan, COALESCE(boss_id, 0), COALESCE(department, ''), phone, created
+	FROM managers WHERE id = $1`, id).Scan(
+		&item.ID,
+		&item.Name,
+		&item.Salary,
+		&item.Plan,
+		&item.BossID,
+		&item.Department,
+		&item.Phone,
+		&item.Created)
+	if err == pgx.ErrNoRows {
+		return nil, types.ErrNotFound
+	}
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+
+	return item, nil
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/bdaler/crud/pkg/types"
+	"github.com/jackc/pgx/v4"
+)
+
+// insertPayments must be called in the transaction which locked or created the sale.
+func insertPayments(ctx context.Context, tx pgx.Tx, saleID int64, payments []*types.Payment) error {
+	for _, payment := range payments {
+		payment.SaleID = saleID
+		err := tx.QueryRow(
+			ctx,
+			`INSERT INTO payments(sale_id, tender, amount, tendered, change, reference, manager_id)
+			VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, 0)) RETURNING id, created`,
+			payment.SaleID,
+			payment.Tender,
+			payment.Amount.Amount,
+			payment.Tendered.Amount,
+			payment.Change.Amount,
+			payment.Reference,
+			payment.ManagerID).Scan(&payment.ID, &payment.Created)
+		if err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+	}
+	return nil
+}
+
+// loadPayments appends payments to sales by id, amounts are in currency of the sale.
+func loadPayments(ctx context.Context, q querier, byID map[int64]*types.Sale, ids []int64) error {
+	rows, err := q.Query(ctx, `SELECT id, sale_id, tender, amount, tendered, change, reference, COALESCE(manager_id, 0), created
+	FROM payments
+	WHERE sale_id = ANY($1)
+	ORDER BY id`, ids)
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		payment := &types.Payment{}
+		err = rows.Scan(
+			&payment.ID,
+			&payment.SaleID,
+			&payment.Tender,
+			&payment.Amount.Amount,
+			&payment.Tendered.Amount,
+			&payment.Change.Amount,
+			&payment.Reference,
+			&payment.ManagerID,
+			&payment.Created)
+		if err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+		sale := byID[payment.SaleID]
+		payment.Amount.Currency = sale.Currency
+		payment.Tendered.Currency = sale.Currency
+		payment.Change.Currency = sale.Currency
+		sale.Payments = append(sale.Payments, payment)
+	}
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return types.ErrInternal
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/bdaler/crud/pkg/types"
+	"github.com/bdaler/crud/pkg/utils"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"time"
+)
+
+// priceColumns need product_prices joined with products for currency.
+const priceColumns = `pp.id, pp.product_id, pp.price, p.currency, pp.effective_from, pp.effective_to,
+	COALESCE(pp.manager_id, 0), pp.created`
+
+type PriceRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPriceRepository(pool *pgxpool.Pool) *PriceRepository {
+	return &PriceRepository{pool: pool}
+}
+
+func scanPrice(row rowScanner) (*types.ProductPrice, error) {
+	item := &types.ProductPrice{}
+	err := row.Scan(&item.ID, &item.ProductID, &item.Price.Amount, &item.Price.Currency, &item.EffectiveFrom, &item.EffectiveTo, &item.ManagerID, &item.Created)
+	if err == pgx.ErrNoRows {
+		return nil, types.ErrNotFound
+	}
+	if utils.IsForeignKeyViolation(err) {
+		return nil, types.ErrNotFound
+	}
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	return item, nil
+}
+
+// changePrice must be called in the same transaction which saves the product,
+// the regular price in effect ends now and the new one starts. Scheduled
+// prices with an end (promotions) are left as they are.
+func changePrice(ctx context.Context, tx pgx.Tx, productID int64, price int64, managerID int64) error {
+	_, err := tx.Exec(ctx, `UPDATE product_prices SET effective_to = `+utcNow+`
+	WHERE product_id = $1 AND effective_from < `+utcNow+` AND effective_to IS NULL`, productID)
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO product_prices(product_id, price, effective_from, manager_id)
+	VALUES ($1, $2, `+utcNow+`, NULLIF($3, 0))`, productID, price, managerID)
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+	return nil
+}
+
+func (r *PriceRepository) Schedule(ctx context.Context, price *types.ProductPrice) (*types.ProductPrice, error) {
+	sql := `WITH pp AS (
+		INSERT INTO product_prices(product_id, price, effective_from, effective_to, manager_id)
+		VALUES ($1, $2, $3, $4, NULLIF($5, 0)) RETURNING *
+	)
+	SELECT ` + priceColumns + ` FROM pp JOIN products p ON p.id = pp.product_id`
+	return scanPrice(r.pool.QueryRow(ctx, sql,
+		price.ProductID,
+		price.Price.Amount,
+		price.EffectiveFrom,
+		price.EffectiveTo,
+		price.ManagerID))
+}
+
+func (r *PriceRepository) ByProduct(ctx context.Context, productID int64) ([]*types.ProductPrice, error) {
+	exists := false
+	err := r.pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM products WHERE id = $1)`, productID).Scan(&exists)
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	if !exists {
+		return nil, types.ErrNotFound
+	}
+
+	rows, err := r.pool.Query(ctx, `SELECT `+priceColumns+` FROM product_prices pp
+	JOIN products p ON p.id = pp.product_id
+	WHERE pp.product_id = $1 ORDER BY pp.effective_from, pp.id`, productID)
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	defer rows.Close()
+
+	items := make([]*types.ProductPrice, 0)
+	for rows.Next() {
+		item, err := scanPrice(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return nil, types.ErrInternal
+	}
+
+	return items, nil
+}
+
+func (r *PriceRepository) At(ctx context.Context, productID int64, at time.Time) (*types.ProductPrice, error) {
+	sql := `SELECT ` + priceColumns + ` FROM product_prices pp
+	JOIN products p ON p.id = pp.product_id
+	WHERE pp.product_id = $1 AND pp.effective_from <= $2 AND (pp.effective_to IS NULL OR pp.effective_to > $2)
+	ORDER BY pp.effective_from DESC, pp.id DESC
+	LIMIT 1`
+	return scanPrice(r.pool.QueryRow(ctx, sql, productID, at))
+}
+
+func (r *PriceRepository) Cancel(ctx context.Context, productID, id int64) error {
+	return inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		started := false
+		err := tx.QueryRow(ctx, `SELECT effective_from <= `+utcNow+` FROM product_prices
+		WHERE id = $1 AND product_id = $2 FOR UPDATE`, id, productID).Scan(&started)
+		if err == pgx.ErrNoRows {
+			return types.ErrNotFound
+		}
+		if err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+		if started {
+			return types.ErrPriceInEffect
+		}
+
+		if _, err = tx.Exec(ctx, `DELETE FROM product_prices WHERE id = $1`, id); err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+		return nil
+	})
+}
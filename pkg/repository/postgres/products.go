@@ -0,0 +1,312 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/bdaler/crud/pkg/paging"
+	"github.com/bdaler/crud/pkg/types"
+	"github.com/bdaler/crud/pkg/utils"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"strings"
+)
+
+// utcNow is the current moment in UTC, TIMESTAMP columns hold UTC time
+// whatever time zone the server runs in.
+const utcNow = `(now() AT TIME ZONE 'UTC')`
+
+// reservedQty is quantity of products row held by active reservations.
+const reservedQty = `COALESCE((SELECT SUM(rsp.qty)
+	FROM reservations_positions rsp
+	JOIN reservations rs ON rs.id = rsp.reservation_id
+	WHERE rsp.product_id = products.id AND rs.expire > ` + utcNow + `), 0)`
+
+// othersReservedQty is reservedQty without reservations of customer $2,
+// reservations without customer are never the buyer's own.
+const othersReservedQty = `COALESCE((SELECT SUM(rsp.qty)
+	FROM reservations_positions rsp
+	JOIN reservations rs ON rs.id = rsp.reservation_id
+	WHERE rsp.product_id = products.id AND rs.expire > ` + utcNow + `
+	AND (rs.customer_id = 0 OR rs.customer_id <> $2)), 0)`
+
+// effectivePrice is price of products row at the moment, see product_prices.
+const effectivePrice = `product_price(id, ` + utcNow + `)`
+
+const productColumns = `id, name, ` + effectivePrice + `, currency, qty, qty - ` + reservedQty + `, reorder_level, active, created`
+
+type ProductRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewProductRepository(pool *pgxpool.Pool) *ProductRepository {
+	return &ProductRepository{pool: pool}
+}
+
+// productDest returns scan destinations matching productColumns.
+func productDest(item *types.Product) []interface{} {
+	return []interface{}{&item.ID, &item.Name, &item.Price.Amount, &item.Price.Currency, &item.Qty, &item.Available, &item.ReorderLevel, &item.Active, &item.Created}
+}
+
+func scanProduct(row rowScanner) (*types.Product, error) {
+	item := &types.Product{}
+	err := row.Scan(productDest(item)...)
+	if err == pgx.ErrNoRows {
+		return nil, types.ErrNotFound
+	}
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	return item, nil
+}
+
+func (r *ProductRepository) Save(ctx context.Context, product *types.Product, managerID int64) (*types.Product, error) {
+	var result *types.Product
+	err := inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		movement := &types.StockMovement{ManagerID: managerID}
+		priceChanged := true
+		id := product.ID
+		if id == 0 {
+			err := tx.QueryRow(ctx, `INSERT INTO products(name, qty, price, currency, reorder_level) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+				product.Name, product.Qty, product.Price.Amount, product.Price.Currency, product.ReorderLevel).Scan(&id)
+			if err != nil {
+				logger.Error(err)
+				return types.ErrInternal
+			}
+			movement.Kind = types.MovementReceipt
+			movement.Qty = product.Qty
+		} else {
+			oldQty, oldPrice := 0, types.Money{}
+			// compared with the regular price, promotions in effect must not look like a change
+			err := tx.QueryRow(ctx, `SELECT qty, price, currency FROM products WHERE id = $1 FOR UPDATE`, product.ID).
+				Scan(&oldQty, &oldPrice.Amount, &oldPrice.Currency)
+			if err == pgx.ErrNoRows {
+				return types.ErrNotFound
+			}
+			if err != nil {
+				logger.Error(err)
+				return types.ErrInternal
+			}
+			if product.Price.Currency != "" && product.Price.Currency != oldPrice.Currency {
+				return types.ErrCurrencyMismatch
+			}
+
+			// products.price keeps the last regular price, promotions don't touch it
+			sql := `UPDATE products SET name = $1, qty = $2, reorder_level = $3 WHERE id = $4`
+			args := []interface{}{product.Name, product.Qty, product.ReorderLevel, product.ID}
+			priceChanged = product.Price.Amount != oldPrice.Amount
+			if priceChanged {
+				sql = `UPDATE products SET name = $1, qty = $2, reorder_level = $3, price = $5 WHERE id = $4`
+				args = append(args, product.Price.Amount)
+			}
+			if _, err = tx.Exec(ctx, sql, args...); err != nil {
+				logger.Error(err)
+				return types.ErrInternal
+			}
+			movement.Kind = types.MovementAdjustment
+			movement.Qty = product.Qty - oldQty
+		}
+
+		if priceChanged {
+			if err := changePrice(ctx, tx, id, product.Price.Amount, managerID); err != nil {
+				return err
+			}
+		}
+		if movement.Qty != 0 {
+			movement.ProductID = id
+			if err := recordMovement(ctx, tx, movement); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		result, err = scanProduct(tx.QueryRow(ctx, `SELECT `+productColumns+` FROM products WHERE id = $1`, id))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+var productSorts = map[string]string{
+	"":                "id",
+	types.SortID:      "id",
+	types.SortName:    "name",
+	types.SortPrice:   effectivePrice,
+	types.SortCreated: "created",
+}
+
+func (r *ProductRepository) List(ctx context.Context, filter *types.ProductFilter) (*types.ProductPage, error) {
+	column, ok := productSorts[filter.Sort]
+	if !ok {
+		return nil, types.ErrInvalidSort
+	}
+	cursor, err := paging.Decode(filter.After, filter.Sort)
+	if err != nil {
+		return nil, err
+	}
+	limit := paging.Limit(filter.Limit)
+
+	q := &query{}
+	if !filter.WithInactive {
+		q.and("active = TRUE")
+	}
+	if filter.MinPrice > 0 {
+		q.and(effectivePrice + " >= " + q.arg(filter.MinPrice))
+	}
+	if filter.MaxPrice > 0 {
+		q.and(effectivePrice + " <= " + q.arg(filter.MaxPrice))
+	}
+	if filter.InStock {
+		q.and("qty - " + reservedQty + " > 0")
+	}
+	if filter.Name != "" {
+		q.and("name ILIKE " + q.arg(likePattern(filter.Name)))
+	}
+	if filter.CategoryID != 0 {
+		subtree := fmt.Sprintf(categorySubtree, q.arg(filter.CategoryID))
+		q.and("id IN (SELECT product_id FROM products_categories WHERE category_id IN (" + subtree + "))")
+	}
+	if cursor != nil {
+		value, err := paging.SortValue(cursor)
+		if err != nil {
+			return nil, err
+		}
+		q.keyset(column, value, cursor.ID, filter.Desc)
+	}
+
+	sql := `SELECT ` + productColumns + ` FROM products` + q.whereSQL() +
+		orderBy(column, filter.Desc) + ` LIMIT ` + q.arg(limit+1)
+	rows, err := r.pool.Query(ctx, sql, q.args...)
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	defer rows.Close()
+
+	items := make([]*types.Product, 0)
+	for rows.Next() {
+		item, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return nil, types.ErrInternal
+	}
+
+	page := &types.ProductPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = paging.Encode(paging.ProductCursor(page.Items[limit-1], filter.Sort))
+	}
+
+	return page, nil
+}
+
+func (r *ProductRepository) ByID(ctx context.Context, id int64) (*types.Product, error) {
+	sql := `SELECT ` + productColumns + ` FROM products WHERE id = $1`
+	return scanProduct(r.pool.QueryRow(ctx, sql, id))
+}
+
+func (r *ProductRepository) ChangeActive(ctx context.Context, id int64, active bool) (*types.Product, error) {
+	sql := `UPDATE products SET active = $2 WHERE id = $1 RETURNING ` + productColumns
+	return scanProduct(r.pool.QueryRow(ctx, sql, id, active))
+}
+
+// Search matches words by prefix with full-text search and whole query by
+// pg_trgm word similarity, so that "choco" and "chocolate" with a typo are found.
+func (r *ProductRepository) Search(ctx context.Context, search *types.ProductSearch) ([]*types.ProductHit, error) {
+	words := utils.SearchWords(search.Query)
+	if len(words) == 0 {
+		return []*types.ProductHit{}, nil
+	}
+	prefixes := make([]string, 0, len(words))
+	for _, word := range words {
+		prefixes = append(prefixes, word+":*")
+	}
+
+	sql := `SELECT ` + productColumns + `,
+		ts_rank(search, tsq) + word_similarity($2, name) AS rank,
+		ts_headline('simple', name, tsq)
+	FROM products, to_tsquery('simple', $1) AS tsq
+	WHERE active = TRUE AND (search @@ tsq OR $2 <% name)
+	ORDER BY rank DESC, id
+	LIMIT $3`
+	rows, err := r.pool.Query(ctx, sql, strings.Join(prefixes, " & "), strings.Join(words, " "), paging.Limit(search.Limit))
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	defer rows.Close()
+
+	items := make([]*types.ProductHit, 0)
+	for rows.Next() {
+		item := &types.ProductHit{Product: &types.Product{}}
+		if err = rows.Scan(append(productDest(item.Product), &item.Rank, &item.Highlight)...); err != nil {
+			logger.Error(err)
+			return nil, types.ErrInternal
+		}
+		items = append(items, item)
+	}
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return nil, types.ErrInternal
+	}
+
+	return items, nil
+}
+
+func (r *ProductRepository) LowStock(ctx context.Context) ([]*types.Product, error) {
+	sql := `SELECT ` + productColumns + ` FROM products
+	WHERE active = TRUE AND reorder_level > 0 AND qty <= reorder_level
+	ORDER BY id`
+	rows, err := r.pool.Query(ctx, sql)
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	defer rows.Close()
+
+	items := make([]*types.Product, 0)
+	for rows.Next() {
+		item, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return nil, types.ErrInternal
+	}
+
+	return items, nil
+}
+
+func (r *ProductRepository) Purge(ctx context.Context, id int64) error {
+	sql := `DELETE FROM products p
+	WHERE p.id = $1 AND NOT EXISTS(SELECT 1 FROM sales_positions sp WHERE sp.product_id = p.id)
+	RETURNING p.id`
+	err := r.pool.QueryRow(ctx, sql, id).Scan(&id)
+	if err == pgx.ErrNoRows {
+		if _, err = r.ByID(ctx, id); err != nil {
+			return err
+		}
+		return types.ErrProductInUse
+	}
+	if utils.IsForeignKeyViolation(err) {
+		return types.ErrProductInUse
+	}
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/bdaler/crud/pkg/types"
+	"github.com/bdaler/crud/pkg/utils"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"time"
+)
+
+const promoColumns = `id, code, kind, value, currency, COALESCE(product_id, 0), min_total, valid_from, valid_to,
+	max_uses, max_uses_per_customer, (SELECT COUNT(*) FROM sales s WHERE s.promo_code_id = promo_codes.id),
+	active, created`
+
+type PromoRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPromoRepository(pool *pgxpool.Pool) *PromoRepository {
+	return &PromoRepository{pool: pool}
+}
+
+func scanPromo(row rowScanner) (*types.PromoCode, error) {
+	item := &types.PromoCode{}
+	err := row.Scan(
+		&item.ID,
+		&item.Code,
+		&item.Kind,
+		&item.Value,
+		&item.Currency,
+		&item.ProductID,
+		&item.MinTotal,
+		&item.ValidFrom,
+		&item.ValidTo,
+		&item.MaxUses,
+		&item.MaxUsesPerCustomer,
+		&item.Uses,
+		&item.Active,
+		&item.Created)
+	if err == pgx.ErrNoRows {
+		return nil, types.ErrNotFound
+	}
+	if utils.IsUniqueViolation(err) {
+		return nil, types.ErrPromoCodeUsed
+	}
+	// unknown product
+	if utils.IsForeignKeyViolation(err) {
+		return nil, types.ErrNotFound
+	}
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	return item, nil
+}
+
+// applyPromo must be called in the transaction which creates the sale, after
+// prices of positions are set. The code row is locked so that concurrent sales
+// can't exceed usage limits.
+func applyPromo(ctx context.Context, tx pgx.Tx, sale *types.Sale) error {
+	promo, err := scanPromo(tx.QueryRow(ctx, `SELECT `+promoColumns+` FROM promo_codes WHERE code = $1 FOR UPDATE`, sale.PromoCode))
+	if err == types.ErrNotFound {
+		return types.ErrPromoInvalid
+	}
+	if err != nil {
+		return err
+	}
+
+	customerUses := 0
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM sales WHERE promo_code_id = $1 AND customer_id = $2`,
+		promo.ID, sale.CustomerID).Scan(&customerUses)
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+
+	var current time.Time
+	if err = tx.QueryRow(ctx, `SELECT `+utcNow).Scan(&current); err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+	return promo.Apply(sale, customerUses, current)
+}
+
+func (r *PromoRepository) Save(ctx context.Context, promo *types.PromoCode) (*types.PromoCode, error) {
+	if promo.ID == 0 {
+		sql := `INSERT INTO promo_codes(code, kind, value, currency, product_id, min_total, valid_from, valid_to,
+			max_uses, max_uses_per_customer, active)
+		VALUES ($1, $2, $3, $4, NULLIF($5, 0), $6, $7, $8, $9, $10, $11) RETURNING ` + promoColumns
+		return scanPromo(r.pool.QueryRow(ctx, sql,
+			promo.Code,
+			promo.Kind,
+			promo.Value,
+			promo.Currency,
+			promo.ProductID,
+			promo.MinTotal,
+			promo.ValidFrom,
+			promo.ValidTo,
+			promo.MaxUses,
+			promo.MaxUsesPerCustomer,
+			promo.Active))
+	}
+
+	sql := `UPDATE promo_codes SET code = $2, kind = $3, value = $4, currency = $5, product_id = NULLIF($6, 0),
+		min_total = $7, valid_from = $8, valid_to = $9, max_uses = $10, max_uses_per_customer = $11, active = $12
+	WHERE id = $1 RETURNING ` + promoColumns
+	return scanPromo(r.pool.QueryRow(ctx, sql,
+		promo.ID,
+		promo.Code,
+		promo.Kind,
+		promo.Value,
+		promo.Currency,
+		promo.ProductID,
+		promo.MinTotal,
+		promo.ValidFrom,
+		promo.ValidTo,
+		promo.MaxUses,
+		promo.MaxUsesPerCustomer,
+		promo.Active))
+}
+
+func (r *PromoRepository) ByID(ctx context.Context, id int64) (*types.PromoCode, error) {
+	return scanPromo(r.pool.QueryRow(ctx, `SELECT `+promoColumns+` FROM promo_codes WHERE id = $1`, id))
+}
+
+func (r *PromoRepository) All(ctx context.Context) ([]*types.PromoCode, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+promoColumns+` FROM promo_codes ORDER BY id DESC`)
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	defer rows.Close()
+
+	items := make([]*types.PromoCode, 0)
+	for rows.Next() {
+		item, err := scanPromo(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return nil, types.ErrInternal
+	}
+
+	return items, nil
+}
@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"strconv"
+	"strings"
+)
+
+// query collects WHERE conditions with positional arguments.
+type query struct {
+	where []string
+	args  []interface{}
+}
+
+func (q *query) arg(value interface{}) string {
+	q.args = append(q.args, value)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+func (q *query) and(condition string) {
+	q.where = append(q.where, condition)
+}
+
+func (q *query) whereSQL() string {
+	if len(q.where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.where, " AND ")
+}
+
+// keyset adds condition for rows after (value, id) in given order,
+// column is nil-valued when sorting by id only.
+func (q *query) keyset(column string, value interface{}, id int64, desc bool) {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	if column == "id" || value == nil {
+		q.and("id " + op + " " + q.arg(id))
+		return
+	}
+	q.and("(" + column + ", id) " + op + " (" + q.arg(value) + ", " + q.arg(id) + ")")
+}
+
+func orderBy(column string, desc bool) string {
+	dir := " ASC"
+	if desc {
+		dir = " DESC"
+	}
+	if column == "id" {
+		return " ORDER BY id" + dir
+	}
+	return " ORDER BY " + column + dir + ", id" + dir
+}
+
+func likePattern(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(value) + "%"
+}
@@ -0,0 +1,276 @@
+package postgres
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/bdaler/crud/pkg/types"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"sort"
+	"time"
+)
+
+type ReservationRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewReservationRepository(pool *pgxpool.Pool) *ReservationRepository {
+	return &ReservationRepository{pool: pool}
+}
+
+func (r *ReservationRepository) Create(ctx context.Context, reservation *types.Reservation, ttl time.Duration) (*types.Reservation, error) {
+	err := inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		requested := make(map[int64]int)
+		for _, position := range reservation.Positions {
+			requested[position.ProductID] += position.Qty
+		}
+		ids := make([]int64, 0, len(requested))
+		for id := range requested {
+			ids = append(ids, id)
+		}
+		// same order as in sales so that they can't deadlock
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		for _, id := range ids {
+			if err := checkAvailable(ctx, tx, id, requested[id]); err != nil {
+				return err
+			}
+		}
+
+		err := tx.QueryRow(ctx, `INSERT INTO reservations(customer_id, manager_id, expire)
+		VALUES ($1, NULLIF($2, 0), `+utcNow+` + make_interval(secs => $3))
+		RETURNING id, expire, created`,
+			reservation.CustomerID,
+			reservation.ManagerID,
+			ttl.Seconds()).Scan(&reservation.ID, &reservation.Expire, &reservation.Created)
+		if err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+
+		for _, position := range reservation.Positions {
+			position.ReservationID = reservation.ID
+			err = tx.QueryRow(ctx,
+				`INSERT INTO reservations_positions(reservation_id, product_id, qty) VALUES ($1, $2, $3) RETURNING id`,
+				position.ReservationID,
+				position.ProductID,
+				position.Qty).Scan(&position.ID)
+			if err != nil {
+				logger.Error(err)
+				return types.ErrInternal
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+// checkAvailable locks product row so that nobody takes its stock
+// until the transaction ends.
+func checkAvailable(ctx context.Context, tx pgx.Tx, productID int64, qty int) error {
+	active := false
+	available := 0
+	err := tx.QueryRow(ctx, `SELECT active FROM products WHERE id = $1 FOR UPDATE`, productID).Scan(&active)
+	if err == nil {
+		err = tx.QueryRow(ctx, `SELECT qty - `+reservedQty+` FROM products WHERE id = $1`, productID).Scan(&available)
+	}
+	if err == pgx.ErrNoRows {
+		return types.ErrNotFound
+	}
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+	if !active {
+		return types.ErrInvalidPosition
+	}
+	if available < qty {
+		return types.ErrNotEnoughQty
+	}
+	return nil
+}
+
+func (r *ReservationRepository) ByID(ctx context.Context, id int64) (*types.Reservation, error) {
+	item := &types.Reservation{}
+	err := r.pool.QueryRow(ctx,
+		`SELECT id, customer_id, COALESCE(manager_id, 0), expire, created FROM reservations WHERE id = $1`, id).
+		Scan(&item.ID, &item.CustomerID, &item.ManagerID, &item.Expire, &item.Created)
+	if err == pgx.ErrNoRows {
+		return nil, types.ErrNotFound
+	}
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+
+	rows, err := r.pool.Query(ctx,
+		`SELECT id, reservation_id, product_id, qty FROM reservations_positions WHERE reservation_id = $1 ORDER BY id`, id)
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	defer rows.Close()
+
+	item.Positions = make([]*types.ReservationPosition, 0)
+	for rows.Next() {
+		position := &types.ReservationPosition{}
+		if err = rows.Scan(&position.ID, &position.ReservationID, &position.ProductID, &position.Qty); err != nil {
+			logger.Error(err)
+			return nil, types.ErrInternal
+		}
+		item.Positions = append(item.Positions, position)
+	}
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return nil, types.ErrInternal
+	}
+
+	return item, nil
+}
+
+func (r *ReservationRepository) Delete(ctx context.Context, id int64) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM reservations WHERE id = $1`, id)
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+	if tag.RowsAffected() == 0 {
+		return types.ErrNotFound
+	}
+	return nil
+}
+
+func (r *ReservationRepository) Confirm(ctx context.Context, id, managerID int64) (*types.Sale, error) {
+	sale := &types.Sale{}
+	err := inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		var ownerID int64
+		expired := false
+		err := tx.QueryRow(ctx, `SELECT customer_id, COALESCE(manager_id, 0), expire <= `+utcNow+`
+		FROM reservations WHERE id = $1 FOR UPDATE`, id).Scan(&sale.CustomerID, &ownerID, &expired)
+		if err == pgx.ErrNoRows {
+			return types.ErrNotFound
+		}
+		if err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+		if expired {
+			return types.ErrReservationExpired
+		}
+		sale.ManagerID = managerID
+		if sale.ManagerID == 0 {
+			sale.ManagerID = ownerID
+		}
+
+		rows, err := tx.Query(ctx, `SELECT rsp.product_id, rsp.qty
+		FROM reservations_positions rsp
+		WHERE rsp.reservation_id = $1
+		ORDER BY rsp.id`, id)
+		if err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+		for rows.Next() {
+			position := &types.SalePosition{}
+			if err = rows.Scan(&position.ProductID, &position.Qty); err != nil {
+				rows.Close()
+				logger.Error(err)
+				return types.ErrInternal
+			}
+			sale.Positions = append(sale.Positions, position)
+		}
+		rows.Close()
+		if rows.Err() != nil {
+			logger.Error(rows.Err())
+			return types.ErrInternal
+		}
+
+		// held quantity must not count against the sale made from it
+		if _, err = tx.Exec(ctx, `DELETE FROM reservations WHERE id = $1`, id); err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+
+		return createSale(ctx, tx, sale)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sale, nil
+}
+
+func (r *ReservationRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM reservations WHERE expire <= `+utcNow)
+	if err != nil {
+		logger.Error(err)
+		return 0, types.ErrInternal
+	}
+	return tag.RowsAffected(), nil
+}
+
+// useReserved takes qty of the product bought by the customer out of their active
+// reservations, the oldest first. Reservations left without positions are released.
+func useReserved(ctx context.Context, tx pgx.Tx, customerID, productID int64, qty int) error {
+	if customerID == 0 {
+		return nil
+	}
+	rows, err := tx.Query(ctx, `SELECT rsp.id, rsp.qty
+	FROM reservations_positions rsp
+	JOIN reservations rs ON rs.id = rsp.reservation_id
+	WHERE rs.customer_id = $1 AND rsp.product_id = $2 AND rs.expire > `+utcNow+`
+	ORDER BY rs.id, rsp.id
+	FOR UPDATE OF rsp`, customerID, productID)
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+	// position id -> qty left in it, used positions are deleted
+	left := make(map[int64]int)
+	for rows.Next() && qty > 0 {
+		var id int64
+		var reserved int
+		if err = rows.Scan(&id, &reserved); err != nil {
+			rows.Close()
+			logger.Error(err)
+			return types.ErrInternal
+		}
+		if reserved > qty {
+			left[id] = reserved - qty
+			qty = 0
+		} else {
+			left[id] = 0
+			qty -= reserved
+		}
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return types.ErrInternal
+	}
+
+	for id, reserved := range left {
+		if reserved == 0 {
+			_, err = tx.Exec(ctx, `DELETE FROM reservations_positions WHERE id = $1`, id)
+		} else {
+			_, err = tx.Exec(ctx, `UPDATE reservations_positions SET qty = $2 WHERE id = $1`, id, reserved)
+		}
+		if err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM reservations rs WHERE rs.customer_id = $1
+	AND NOT EXISTS (SELECT 1 FROM reservations_positions rsp WHERE rsp.reservation_id = rs.id)`, customerID)
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+	return nil
+}
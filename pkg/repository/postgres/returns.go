@@ -0,0 +1,241 @@
+package postgres
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/bdaler/crud/pkg/types"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type ReturnRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewReturnRepository(pool *pgxpool.Pool) *ReturnRepository {
+	return &ReturnRepository{pool: pool}
+}
+
+type soldPosition struct {
+	productID int64
+	price     int64
+	left      int
+}
+
+func (r *ReturnRepository) Create(ctx context.Context, ret *types.Return) (*types.Return, error) {
+	err := inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		// lock the sale so that concurrent returns of it are serialized
+		currency := ""
+		voided := false
+		err := tx.QueryRow(ctx, `SELECT id, currency, voided IS NOT NULL FROM sales WHERE id = $1 FOR UPDATE`, ret.SaleID).
+			Scan(&ret.SaleID, &currency, &voided)
+		if err == pgx.ErrNoRows {
+			return types.ErrNotFound
+		}
+		if err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+		if voided {
+			return types.ErrSaleVoided
+		}
+
+		sold, order, err := soldPositions(ctx, tx, ret.SaleID)
+		if err != nil {
+			return err
+		}
+
+		if len(ret.Positions) == 0 {
+			for _, id := range order {
+				if sold[id].left > 0 {
+					ret.Positions = append(ret.Positions, &types.ReturnPosition{SalePositionID: id, Qty: sold[id].left})
+				}
+			}
+			if len(ret.Positions) == 0 {
+				return types.ErrReturnExceedsQty
+			}
+		}
+
+		for _, position := range ret.Positions {
+			item, ok := sold[position.SalePositionID]
+			if !ok {
+				return types.ErrInvalidPosition
+			}
+			if position.Qty > item.left {
+				return types.ErrReturnExceedsQty
+			}
+			item.left -= position.Qty
+			position.ProductID = item.productID
+			position.Price = types.NewMoney(item.price, currency)
+		}
+		if err = ret.CalcTotals(); err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(
+			ctx,
+			`INSERT INTO returns(sale_id, manager_id, reason) VALUES ($1, $2, $3) RETURNING id, created`,
+			ret.SaleID,
+			ret.ManagerID,
+			ret.Reason).Scan(&ret.ID, &ret.Created)
+		if err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+
+		for _, position := range ret.Positions {
+			position.ReturnID = ret.ID
+			err = tx.QueryRow(
+				ctx,
+				`INSERT INTO returns_positions(return_id, sale_position_id, product_id, price, qty)
+				VALUES ($1, $2, $3, $4, $5) RETURNING id, created`,
+				position.ReturnID,
+				position.SalePositionID,
+				position.ProductID,
+				position.Price.Amount,
+				position.Qty).Scan(&position.ID, &position.Created)
+			if err != nil {
+				logger.Error(err)
+				return types.ErrInternal
+			}
+
+			_, err = tx.Exec(ctx, `UPDATE products SET qty = qty + $1 WHERE id = $2`, position.Qty, position.ProductID)
+			if err != nil {
+				logger.Error(err)
+				return types.ErrInternal
+			}
+
+			err = recordMovement(ctx, tx, &types.StockMovement{
+				ProductID: position.ProductID,
+				Kind:      types.MovementReturn,
+				Qty:       position.Qty,
+				ManagerID: ret.ManagerID,
+				SaleID:    ret.SaleID,
+				ReturnID:  ret.ID,
+				Reason:    ret.Reason,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// soldPositions returns positions of the sale with quantity which is still allowed to return.
+func soldPositions(ctx context.Context, tx pgx.Tx, saleID int64) (map[int64]*soldPosition, []int64, error) {
+	// refund is the paid unit price, see types.SalePosition.PaidPrice
+	rows, err := tx.Query(ctx, `SELECT sp.id, sp.product_id,
+		(sp.price * sp.qty - sp.discount + CASE WHEN sp.tax_inclusive THEN 0 ELSE sp.tax END) / sp.qty,
+		sp.qty - COALESCE((SELECT SUM(rp.qty) FROM returns_positions rp WHERE rp.sale_position_id = sp.id), 0)
+	FROM sales_positions sp
+	WHERE sp.sale_id = $1
+	ORDER BY sp.id`, saleID)
+	if err != nil {
+		logger.Error(err)
+		return nil, nil, types.ErrInternal
+	}
+	defer rows.Close()
+
+	sold := make(map[int64]*soldPosition)
+	order := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		item := &soldPosition{}
+		if err = rows.Scan(&id, &item.productID, &item.price, &item.left); err != nil {
+			logger.Error(err)
+			return nil, nil, types.ErrInternal
+		}
+		sold[id] = item
+		order = append(order, id)
+	}
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return nil, nil, types.ErrInternal
+	}
+
+	return sold, order, nil
+}
+
+func (r *ReturnRepository) BySale(ctx context.Context, saleID int64) ([]*types.Return, error) {
+	items := make([]*types.Return, 0)
+	rows, err := r.pool.Query(
+		ctx,
+		`SELECT r.id, r.sale_id, r.manager_id, r.reason, s.currency, r.created
+		FROM returns r
+		JOIN sales s ON s.id = r.sale_id
+		WHERE r.sale_id = $1
+		ORDER BY r.id`,
+		saleID)
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	defer rows.Close()
+
+	byID := make(map[int64]*types.Return)
+	currency := ""
+	for rows.Next() {
+		item := &types.Return{Positions: make([]*types.ReturnPosition, 0)}
+		if err = rows.Scan(&item.ID, &item.SaleID, &item.ManagerID, &item.Reason, &currency, &item.Created); err != nil {
+			logger.Error(err)
+			return nil, types.ErrInternal
+		}
+		items = append(items, item)
+		byID[item.ID] = item
+	}
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return nil, types.ErrInternal
+	}
+	rows.Close()
+
+	rows, err = r.pool.Query(ctx, `SELECT rp.id, rp.return_id, rp.sale_position_id, rp.product_id, rp.price, rp.qty, rp.created
+	FROM returns_positions rp
+	JOIN returns r ON r.id = rp.return_id
+	WHERE r.sale_id = $1
+	ORDER BY rp.id`, saleID)
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		position := &types.ReturnPosition{}
+		err = rows.Scan(
+			&position.ID,
+			&position.ReturnID,
+			&position.SalePositionID,
+			&position.ProductID,
+			&position.Price.Amount,
+			&position.Qty,
+			&position.Created)
+		if err != nil {
+			logger.Error(err)
+			return nil, types.ErrInternal
+		}
+		position.Price.Currency = currency
+		ret := byID[position.ReturnID]
+		ret.Positions = append(ret.Positions, position)
+	}
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return nil, types.ErrInternal
+	}
+
+	for _, item := range items {
+		if err = item.CalcTotals(); err != nil {
+			logger.Error(err)
+			return nil, types.ErrInternal
+		}
+	}
+
+	return items, nil
+}
@@ -0,0 +1,531 @@
+package postgres
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/bdaler/crud/pkg/paging"
+	"github.com/bdaler/crud/pkg/types"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"sort"
+	"time"
+)
+
+type SaleRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewSaleRepository(pool *pgxpool.Pool) *SaleRepository {
+	return &SaleRepository{pool: pool}
+}
+
+func (r *SaleRepository) Create(ctx context.Context, sale *types.Sale) (*types.Sale, error) {
+	err := inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := createSale(ctx, tx, sale); err != nil {
+			return err
+		}
+		for _, position := range sale.Positions {
+			if err := useReserved(ctx, tx, sale.CustomerID, position.ProductID, position.Qty); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sale, nil
+}
+
+// createSale fills prices, discounts, taxes and totals of the sale.
+func createSale(ctx context.Context, tx pgx.Tx, sale *types.Sale) error {
+	sale.LowStock = nil
+	// lock products in a stable order so that concurrent sales can't deadlock
+	positions := make([]*types.SalePosition, len(sale.Positions))
+	copy(positions, sale.Positions)
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i].ProductID < positions[j].ProductID
+	})
+
+	requested := make(map[int64]int)
+	for _, position := range positions {
+		requested[position.ProductID] += position.Qty
+		if err := lockSalePosition(ctx, tx, position, requested[position.ProductID], sale.CustomerID); err != nil {
+			return err
+		}
+	}
+
+	if sale.PromoCode != "" {
+		if err := applyPromo(ctx, tx, sale); err != nil {
+			return err
+		}
+	}
+
+	productIDs := make([]int64, 0, len(requested))
+	for productID := range requested {
+		productIDs = append(productIDs, productID)
+	}
+	rules, err := taxRules(ctx, tx, productIDs)
+	if err != nil {
+		return err
+	}
+	if err = sale.ApplyTax(rules); err != nil {
+		return err
+	}
+	payments := sale.Payments
+	sale.Payments = make([]*types.Payment, 0, len(payments))
+	if err = sale.CalcTotals(); err != nil {
+		return err
+	}
+	// a sale paid at once must be paid in full
+	if len(payments) != 0 {
+		if err = sale.Pay(payments); err != nil {
+			return err
+		}
+		if !sale.Due.IsZero() {
+			return types.ErrPaymentShort
+		}
+	}
+
+	sql := `INSERT INTO sales(manager_id, customer_id, promo_code_id, currency, tax)
+	VALUES (NULLIF($1, 0), $2, NULLIF($3, 0), $4, $5)
+	RETURNING id, created`
+	err = tx.QueryRow(ctx, sql, sale.ManagerID, sale.CustomerID, sale.PromoCodeID, sale.Currency, sale.Tax.Amount).
+		Scan(&sale.ID, &sale.Created)
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+
+	for _, position := range positions {
+		position.SaleID = sale.ID
+		if err = makeSalePosition(ctx, tx, sale, position); err != nil {
+			return err
+		}
+
+		err = recordMovement(ctx, tx, &types.StockMovement{
+			ProductID:  position.ProductID,
+			Kind:       types.MovementSale,
+			Qty:        -position.Qty,
+			ManagerID:  sale.ManagerID,
+			CustomerID: sale.CustomerID,
+			SaleID:     sale.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return insertPayments(ctx, tx, sale.ID, sale.Payments)
+}
+
+// lockSalePosition locks product row, checks that requested qty of it (by all
+// positions so far) can be sold and sets position price.
+func lockSalePosition(ctx context.Context, tx pgx.Tx, position *types.SalePosition, requested int, customerID int64) error {
+	active := false
+	qty := 0
+	// price is the one in effect at the time of sale, not the client supplied one
+	err := tx.QueryRow(
+		ctx, `SELECT qty, active, `+effectivePrice+`, currency FROM products WHERE id = $1 FOR UPDATE`, position.ProductID).
+		Scan(&qty, &active, &position.Price.Amount, &position.Price.Currency)
+	if err == nil {
+		// quantity held by others' reservations is not for sale, the buyer's own is
+		var reserved int
+		err = tx.QueryRow(
+			ctx, `SELECT `+othersReservedQty+` FROM products WHERE id = $1`, position.ProductID, customerID).
+			Scan(&reserved)
+		qty -= reserved
+	}
+	if err == pgx.ErrNoRows {
+		return types.ErrNotFound
+	}
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+	if !active {
+		return types.ErrInvalidPosition
+	}
+	if qty < requested {
+		return types.ErrNotEnoughQty
+	}
+	return nil
+}
+
+// makeSalePosition must be called after lockSalePosition in the same transaction,
+// products it drops to the reorder level are added to sale.LowStock.
+func makeSalePosition(ctx context.Context, tx pgx.Tx, sale *types.Sale, position *types.SalePosition) error {
+	product := &types.Product{ID: position.ProductID}
+	err := tx.QueryRow(
+		ctx, `UPDATE products SET qty = qty - $1 WHERE id = $2 RETURNING name, qty, reorder_level`,
+		position.Qty,
+		position.ProductID).Scan(&product.Name, &product.Qty, &product.ReorderLevel)
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+	sale.NoteStock(product, position.Qty)
+
+	err = tx.QueryRow(
+		ctx,
+		`INSERT INTO sales_positions(sale_id, product_id, qty, price, discount, tax_rate, tax_inclusive, tax)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created`,
+		position.SaleID,
+		position.ProductID,
+		position.Qty,
+		position.Price.Amount,
+		position.Discount.Amount,
+		position.TaxRate,
+		position.TaxInclusive,
+		position.Tax.Amount).Scan(&position.ID, &position.Created)
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+
+	return nil
+}
+
+func (r *SaleRepository) TotalByManager(ctx context.Context, managerID int64) ([]types.Money, error) {
+	// returns are attributed to the manager who made the sale
+	sqlstmt := `
+	SELECT s.currency, SUM(s.total)::BIGINT FROM (
+		SELECT s.currency, sp.qty * sp.price - sp.discount + CASE WHEN sp.tax_inclusive THEN 0 ELSE sp.tax END AS total
+		FROM sales s
+		JOIN sales_positions sp ON sp.sale_id = s.id
+		WHERE s.manager_id = $1 AND s.voided IS NULL
+		UNION ALL
+		SELECT s.currency, -rp.qty * rp.price
+		FROM returns r
+		JOIN returns_positions rp ON rp.return_id = r.id
+		JOIN sales s ON s.id = r.sale_id
+		WHERE s.manager_id = $1 AND s.voided IS NULL
+	) s
+	GROUP BY s.currency
+	ORDER BY s.currency`
+
+	rows, err := r.pool.Query(ctx, sqlstmt, managerID)
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	defer rows.Close()
+
+	totals := make([]types.Money, 0, 1)
+	for rows.Next() {
+		total := types.Money{}
+		if err = rows.Scan(&total.Currency, &total.Amount); err != nil {
+			logger.Error(err)
+			return nil, types.ErrInternal
+		}
+		totals = append(totals, total)
+	}
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return nil, types.ErrInternal
+	}
+	return totals, nil
+}
+
+const saleSelect = `SELECT s.id, COALESCE(s.manager_id, 0), s.customer_id, COALESCE(s.promo_code_id, 0), COALESCE(pc.code, ''),
+	s.currency, s.voided, s.created, c.id, c.name, c.phone, c.active, c.created
+	FROM sales s
+	LEFT JOIN customers c ON c.id = s.customer_id
+	LEFT JOIN promo_codes pc ON pc.id = s.promo_code_id`
+
+func scanSale(row rowScanner) (*types.Sale, error) {
+	item := &types.Sale{}
+	var customerID *int64
+	var customerName, customerPhone *string
+	var customerActive *bool
+	var customerCreated *time.Time
+	err := row.Scan(
+		&item.ID,
+		&item.ManagerID,
+		&item.CustomerID,
+		&item.PromoCodeID,
+		&item.PromoCode,
+		&item.Currency,
+		&item.Voided,
+		&item.Created,
+		&customerID,
+		&customerName,
+		&customerPhone,
+		&customerActive,
+		&customerCreated)
+	if err == pgx.ErrNoRows {
+		return nil, types.ErrNotFound
+	}
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	if customerID != nil {
+		item.Customer = &types.Customer{
+			ID:      *customerID,
+			Name:    *customerName,
+			Phone:   *customerPhone,
+			Active:  *customerActive,
+			Created: *customerCreated,
+		}
+	}
+	return item, nil
+}
+
+func (r *SaleRepository) ByID(ctx context.Context, id int64) (*types.Sale, error) {
+	item, err := scanSale(r.pool.QueryRow(ctx, saleSelect+` WHERE s.id = $1`, id))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = loadPositions(ctx, r.pool, []*types.Sale{item}); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (r *SaleRepository) List(ctx context.Context, filter *types.SaleFilter) (*types.SalePage, error) {
+	cursor, err := paging.Decode(filter.After, types.SortCreated)
+	if err != nil {
+		return nil, err
+	}
+	limit := paging.Limit(filter.Limit)
+
+	q := &query{}
+	if !filter.From.IsZero() {
+		q.and("s.created >= " + q.arg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		q.and("s.created < " + q.arg(filter.To))
+	}
+	if filter.CustomerID != 0 {
+		q.and("s.customer_id = " + q.arg(filter.CustomerID))
+	}
+	if filter.ManagerID != 0 {
+		q.and("s.manager_id = " + q.arg(filter.ManagerID))
+	}
+	if cursor != nil {
+		created, err := paging.TimeValue(cursor)
+		if err != nil {
+			return nil, err
+		}
+		q.and("(s.created, s.id) < (" + q.arg(created) + ", " + q.arg(cursor.ID) + ")")
+	}
+
+	sql := saleSelect + q.whereSQL() + ` ORDER BY s.created DESC, s.id DESC LIMIT ` + q.arg(limit+1)
+	rows, err := r.pool.Query(ctx, sql, q.args...)
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	defer rows.Close()
+
+	items := make([]*types.Sale, 0)
+	for rows.Next() {
+		item, err := scanSale(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return nil, types.ErrInternal
+	}
+	rows.Close()
+
+	page := &types.SalePage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = paging.Encode(paging.SaleCursor(page.Items[limit-1]))
+	}
+
+	if err = loadPositions(ctx, r.pool, page.Items); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func (r *SaleRepository) AssignManager(ctx context.Context, saleID, managerID int64) (*types.Sale, error) {
+	tag, err := r.pool.Exec(ctx, `UPDATE sales SET manager_id = $2 WHERE id = $1 AND manager_id IS NULL`, saleID, managerID)
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+
+	sale, err := r.ByID(ctx, saleID)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, types.ErrSaleAssigned
+	}
+
+	return sale, nil
+}
+
+// lockSale locks the sale row for the rest of transaction and loads it.
+func lockSale(ctx context.Context, tx pgx.Tx, id int64) (*types.Sale, error) {
+	sale, err := scanSale(tx.QueryRow(ctx, saleSelect+` WHERE s.id = $1 FOR UPDATE OF s`, id))
+	if err != nil {
+		return nil, err
+	}
+	if err = loadPositions(ctx, tx, []*types.Sale{sale}); err != nil {
+		return nil, err
+	}
+	return sale, nil
+}
+
+func (r *SaleRepository) Pay(ctx context.Context, saleID int64, payments []*types.Payment) (*types.Sale, error) {
+	var sale *types.Sale
+	err := inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		if sale, err = lockSale(ctx, tx, saleID); err != nil {
+			return err
+		}
+		if err = sale.Pay(payments); err != nil {
+			return err
+		}
+		return insertPayments(ctx, tx, sale.ID, payments)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sale, nil
+}
+
+func (r *SaleRepository) Void(ctx context.Context, saleID, managerID int64) (*types.Sale, error) {
+	var sale *types.Sale
+	err := inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		if sale, err = lockSale(ctx, tx, saleID); err != nil {
+			return err
+		}
+		if sale.Voided != nil {
+			return types.ErrSaleVoided
+		}
+		if len(sale.Payments) != 0 {
+			return types.ErrSaleHasPayments
+		}
+
+		// returned quantity is back in stock already
+		for _, position := range sale.Positions {
+			qty := position.Qty - position.ReturnedQty
+			if qty <= 0 {
+				continue
+			}
+			_, err = tx.Exec(ctx, `UPDATE products SET qty = qty + $1 WHERE id = $2`, qty, position.ProductID)
+			if err != nil {
+				logger.Error(err)
+				return types.ErrInternal
+			}
+			err = recordMovement(ctx, tx, &types.StockMovement{
+				ProductID:  position.ProductID,
+				Kind:       types.MovementVoid,
+				Qty:        qty,
+				ManagerID:  managerID,
+				CustomerID: sale.CustomerID,
+				SaleID:     sale.ID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.QueryRow(ctx, `UPDATE sales SET voided = `+utcNow+` WHERE id = $1 RETURNING voided`, sale.ID).
+			Scan(&sale.Voided)
+		if err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+		if err = sale.CalcTotals(); err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sale, nil
+}
+
+// loadPositions fetches positions and payments of all sales and calculates totals.
+func loadPositions(ctx context.Context, q querier, sales []*types.Sale) error {
+	if len(sales) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*types.Sale, len(sales))
+	ids := make([]int64, 0, len(sales))
+	for _, sale := range sales {
+		sale.Positions = make([]*types.SalePosition, 0)
+		sale.Payments = make([]*types.Payment, 0)
+		byID[sale.ID] = sale
+		ids = append(ids, sale.ID)
+	}
+
+	sql := `SELECT sp.id, sp.sale_id, sp.product_id, p.name, sp.price, sp.qty, sp.discount,
+		sp.tax_rate, sp.tax_inclusive, sp.tax,
+		COALESCE((SELECT SUM(rp.qty) FROM returns_positions rp WHERE rp.sale_position_id = sp.id), 0),
+		sp.created
+	FROM sales_positions sp
+	JOIN products p ON p.id = sp.product_id
+	WHERE sp.sale_id = ANY($1)
+	ORDER BY sp.id`
+	rows, err := q.Query(ctx, sql, ids)
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		position := &types.SalePosition{}
+		err = rows.Scan(
+			&position.ID,
+			&position.SaleID,
+			&position.ProductID,
+			&position.ProductName,
+			&position.Price.Amount,
+			&position.Qty,
+			&position.Discount.Amount,
+			&position.TaxRate,
+			&position.TaxInclusive,
+			&position.Tax.Amount,
+			&position.ReturnedQty,
+			&position.Created)
+		if err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+		sale := byID[position.SaleID]
+		position.Price.Currency = sale.Currency
+		position.Discount.Currency = sale.Currency
+		position.Tax.Currency = sale.Currency
+		sale.Positions = append(sale.Positions, position)
+	}
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return types.ErrInternal
+	}
+	rows.Close()
+
+	if err = loadPayments(ctx, q, byID, ids); err != nil {
+		return err
+	}
+
+	for _, sale := range sales {
+		if err = sale.CalcTotals(); err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/bdaler/crud/pkg/paging"
+	"github.com/bdaler/crud/pkg/types"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type StockRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewStockRepository(pool *pgxpool.Pool) *StockRepository {
+	return &StockRepository{pool: pool}
+}
+
+// recordMovement must be called in the same transaction which changes products.qty.
+func recordMovement(ctx context.Context, tx pgx.Tx, movement *types.StockMovement) error {
+	err := tx.QueryRow(ctx, `INSERT INTO stock_movements(product_id, kind, qty, manager_id, customer_id, sale_id, return_id, reason)
+	VALUES ($1, $2, $3, NULLIF($4, 0), NULLIF($5, 0), NULLIF($6, 0), NULLIF($7, 0), $8)
+	RETURNING id, created`,
+		movement.ProductID,
+		movement.Kind,
+		movement.Qty,
+		movement.ManagerID,
+		movement.CustomerID,
+		movement.SaleID,
+		movement.ReturnID,
+		movement.Reason).Scan(&movement.ID, &movement.Created)
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+	return nil
+}
+
+func (r *StockRepository) Move(ctx context.Context, movement *types.StockMovement) (*types.StockMovement, error) {
+	err := inTx(ctx, r.pool, func(tx pgx.Tx) error {
+		qty := 0
+		err := tx.QueryRow(ctx, `SELECT qty FROM products WHERE id = $1 FOR UPDATE`, movement.ProductID).Scan(&qty)
+		if err == pgx.ErrNoRows {
+			return types.ErrNotFound
+		}
+		if err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+		if qty+movement.Qty < 0 {
+			return types.ErrNotEnoughQty
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE products SET qty = qty + $1 WHERE id = $2`, movement.Qty, movement.ProductID)
+		if err != nil {
+			logger.Error(err)
+			return types.ErrInternal
+		}
+
+		return recordMovement(ctx, tx, movement)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return movement, nil
+}
+
+func (r *StockRepository) Movements(ctx context.Context, filter *types.MovementFilter) (*types.MovementPage, error) {
+	cursor, err := paging.Decode(filter.After, types.SortID)
+	if err != nil {
+		return nil, err
+	}
+	limit := paging.Limit(filter.Limit)
+
+	q := &query{}
+	q.and("product_id = " + q.arg(filter.ProductID))
+	if cursor != nil {
+		q.and("id < " + q.arg(cursor.ID))
+	}
+
+	sql := `SELECT id, product_id, kind, qty, COALESCE(manager_id, 0), COALESCE(customer_id, 0),
+		COALESCE(sale_id, 0), COALESCE(return_id, 0), reason, created
+	FROM stock_movements` + q.whereSQL() + ` ORDER BY id DESC LIMIT ` + q.arg(limit+1)
+	rows, err := r.pool.Query(ctx, sql, q.args...)
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	defer rows.Close()
+
+	items := make([]*types.StockMovement, 0)
+	for rows.Next() {
+		item := &types.StockMovement{}
+		err = rows.Scan(
+			&item.ID,
+			&item.ProductID,
+			&item.Kind,
+			&item.Qty,
+			&item.ManagerID,
+			&item.CustomerID,
+			&item.SaleID,
+			&item.ReturnID,
+			&item.Reason,
+			&item.Created)
+		if err != nil {
+			logger.Error(err)
+			return nil, types.ErrInternal
+		}
+		items = append(items, item)
+	}
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return nil, types.ErrInternal
+	}
+
+	page := &types.MovementPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = paging.Encode(paging.MovementCursor(page.Items[limit-1]))
+	}
+
+	return page, nil
+}
+
+func (r *StockRepository) Mismatches(ctx context.Context) ([]*types.StockMismatch, error) {
+	rows, err := r.pool.Query(ctx, `SELECT p.id, p.name, p.qty, COALESCE(SUM(sm.qty), 0)
+	FROM products p
+	LEFT JOIN stock_movements sm ON sm.product_id = p.id
+	GROUP BY p.id
+	HAVING p.qty <> COALESCE(SUM(sm.qty), 0)
+	ORDER BY p.id`)
+	if err != nil {
+		logger.Error(err)
+		return nil, types.ErrInternal
+	}
+	defer rows.Close()
+
+	items := make([]*types.StockMismatch, 0)
+	for rows.Next() {
+		item := &types.StockMismatch{}
+		if err = rows.Scan(&item.ProductID, &item.Name, &item.Qty, &item.LedgerQty); err != nil {
+			logger.Error(err)
+			return nil, types.ErrInternal
+		}
+		items = append(items, item)
+	}
+	if rows.Err() != nil {
+		logger.Error(rows.Err())
+		return nil, types.ErrInternal
+	}
+
+	return items, nil
+}
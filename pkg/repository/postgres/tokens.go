@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/bdaler/crud/pkg/repository"
+	"github.com/bdaler/crud/pkg/types"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"time"
+)
+
+type TokenRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewTokenRepository(pool *pgxpool.Pool) *TokenRepository {
+	return &TokenRepository{pool: pool}
+}
+
+// tokenTable returns table and owner column for principal, both are constants
+// so it's safe to put them into sql text.
+func tokenTable(principal repository.Principal) (table, idColumn string) {
+	switch principal {
+	case repository.Managers:
+		return "managers_tokens", "manager_id"
+	default:
+		return "customers_tokens", "customer_id"
+	}
+}
+
+func (r *TokenRepository) Save(ctx context.Context, principal repository.Principal, token string, id int64, ttl time.Duration) error {
+	table, idColumn := tokenTable(principal)
+	sqlStmt := `INSERT INTO ` + table + `(token, ` + idColumn + `, expire)
+	VALUES ($1, $2, ` + utcNow + ` + make_interval(secs => $3))`
+	_, err := r.pool.Exec(ctx, sqlStmt, token, id, ttl.Seconds())
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+
+	return nil
+}
+
+func (r *TokenRepository) Find(ctx context.Context, principal repository.Principal, token string) (id int64, expired bool, err error) {
+	table, idColumn := tokenTable(principal)
+	sqlStmt := `SELECT ` + idColumn + `, expire < ` + utcNow + ` FROM ` + table + ` WHERE token = $1`
+	err = r.pool.QueryRow(ctx, sqlStmt, token).Scan(&id, &expired)
+	if err == pgx.ErrNoRows {
+		return 0, false, types.ErrTokenNotFound
+	}
+	if err != nil {
+		logger.Error(err)
+		return 0, false, types.ErrInternal
+	}
+
+	return id, expired, nil
+}
+
+func (r *TokenRepository) Prolong(ctx context.Context, principal repository.Principal, token string, ttl time.Duration) error {
+	table, _ := tokenTable(principal)
+	sqlStmt := `UPDATE ` + table + ` SET expire = ` + utcNow + ` + make_interval(secs => $2) WHERE token = $1`
+	_, err := r.pool.Exec(ctx, sqlStmt, token, ttl.Seconds())
+	if err != nil {
+		logger.Error(err)
+		return types.ErrInternal
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/bdaler/crud/pkg/types"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// querier is either pool or transaction.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// inTx runs fn inside transaction, commits when fn succeeds and rolls back otherwise.
+func inTx(ctx context.Context, pool *pgxpool.Pool, fn func(tx pgx.Tx) error) (err error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(ctx); rbErr != nil {
+				logger.Error(rbErr)
+			}
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func credentials(ctx context.Context, pool *pgxpool.Pool, sqlStatement, phone string) (int64, string, error) {
+	var id int64
+	var hash *string
+	err := pool.QueryRow(ctx, sqlStatement, phone).Scan(&id, &hash)
+	if err == pgx.ErrNoRows {
+		return 0, "", types.ErrNoSuchUser
+	}
+	if err != nil {
+		logger.Error(err)
+		return 0, "", types.ErrInternal
+	}
+	if hash == nil {
+		return id, "", nil
+	}
+	return id, *hash, nil
+}
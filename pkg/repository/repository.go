@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/types"
+	"time"
+)
+
+// Principal tells TokenRepository whose tokens are stored.
+type Principal string
+
+const (
+	Customers Principal = "customers"
+	Managers  Principal = "managers"
+)
+
+// Implementations return sentinel errors from pkg/types
+// (ErrNotFound, ErrPhoneUsed, ErrInternal, ...) so services don't depend on storage.
+
+type CustomerRepository interface {
+	ByID(ctx context.Context, id int64) (*types.Customer, error)
+	All(ctx context.Context) ([]*types.Customer, error)
+	AllActive(ctx context.Context) ([]*types.Customer, error)
+	List(ctx context.Context, filter *types.CustomerFilter) (*types.CustomerPage, error)
+	// Save inserts customer when ID is zero, otherwise updates name, phone and password.
+	Save(ctx context.Context, customer *types.Customer) (*types.Customer, error)
+	// Change updates name, phone and active flag.
+	Change(ctx context.Context, customer *types.Customer) (*types.Customer, error)
+	ChangeActive(ctx context.Context, id int64, active bool) (*types.Customer, error)
+	Delete(ctx context.Context, id int64) (*types.Customer, error)
+	// Credentials returns id and password hash of customer with given phone.
+	Credentials(ctx context.Context, phone string) (int64, string, error)
+}
+
+type ManagerRepository interface {
+	// Create inserts manager along with its roles and returns new id.
+	Create(ctx context.Context, manager *types.Manager) (int64, error)
+	Credentials(ctx context.Context, phone string) (int64, string, error)
+	HasAnyRole(ctx context.Context, id int64, roles ...string) (bool, error)
+	// ByID returns manager without password and roles.
+	ByID(ctx context.Context, id int64) (*types.Manager, error)
+}
+
+type ProductRepository interface {
+	// Save inserts product when ID is zero, otherwise updates it. Qty change is
+	// recorded as receipt (new product) or adjustment made by managerID, price
+	// different from the effective one starts a new price from now on. Currency
+	// of the product can't change, other one fails with types.ErrCurrencyMismatch.
+	Save(ctx context.Context, product *types.Product, managerID int64) (*types.Product, error)
+	ByID(ctx context.Context, id int64) (*types.Product, error)
+	List(ctx context.Context, filter *types.ProductFilter) (*types.ProductPage, error)
+	ChangeActive(ctx context.Context, id int64, active bool) (*types.Product, error)
+	// Search returns active products matching query words by prefix or
+	// similar to the query (typos), best matches first.
+	Search(ctx context.Context, search *types.ProductSearch) ([]*types.ProductHit, error)
+	// LowStock returns active products at or below their reorder level.
+	LowStock(ctx context.Context) ([]*types.Product, error)
+	// Purge removes product completely, fails with types.ErrProductInUse when it was sold.
+	Purge(ctx context.Context, id int64) error
+}
+
+type CategoryRepository interface {
+	// Save inserts category when ID is zero, otherwise updates it. Fails with
+	// types.ErrNotFound for unknown parent and types.ErrCategoryCycle when
+	// category would become its own ancestor.
+	Save(ctx context.Context, category *types.Category) (*types.Category, error)
+	ByID(ctx context.Context, id int64) (*types.Category, error)
+	// All returns flat list ordered by name.
+	All(ctx context.Context) ([]*types.Category, error)
+	// Delete fails with types.ErrCategoryNotEmpty when category has subcategories.
+	Delete(ctx context.Context, id int64) error
+	ByProduct(ctx context.Context, productID int64) ([]*types.Category, error)
+	// SetProductCategories replaces categories of the product.
+	SetProductCategories(ctx context.Context, productID int64, categoryIDs []int64) error
+}
+
+// PriceRepository keeps product_prices history, Product.Price and sale
+// positions use the price effective at the moment.
+type PriceRepository interface {
+	// Schedule adds price for the period, fails with types.ErrNotFound for unknown product.
+	Schedule(ctx context.Context, price *types.ProductPrice) (*types.ProductPrice, error)
+	// ByProduct returns all prices of the product ordered by EffectiveFrom.
+	ByProduct(ctx context.Context, productID int64) ([]*types.ProductPrice, error)
+	// At returns price effective at given moment, types.ErrNotFound when there was none.
+	At(ctx context.Context, productID int64, at time.Time) (*types.ProductPrice, error)
+	// Cancel removes scheduled price, fails with types.ErrPriceInEffect once it has started.
+	Cancel(ctx context.Context, productID, id int64) error
+}
+
+// StockRepository keeps stock_movements ledger, sales and returns record
+// their movements themselves.
+type StockRepository interface {
+	// Move changes products.qty by movement.Qty and records the movement,
+	// fails with types.ErrNotEnoughQty when qty would become negative.
+	Move(ctx context.Context, movement *types.StockMovement) (*types.StockMovement, error)
+	Movements(ctx context.Context, filter *types.MovementFilter) (*types.MovementPage, error)
+	// Mismatches returns products whose qty is not equal to the sum of movements.
+	Mismatches(ctx context.Context) ([]*types.StockMismatch, error)
+}
+
+// PromoRepository keeps promo codes, SaleRepository applies them.
+type PromoRepository interface {
+	// Save inserts promo code when ID is zero, otherwise updates it. Fails with
+	// types.ErrPromoCodeUsed for duplicate code and types.ErrNotFound for unknown product.
+	Save(ctx context.Context, promo *types.PromoCode) (*types.PromoCode, error)
+	ByID(ctx context.Context, id int64) (*types.PromoCode, error)
+	// All returns promo codes with their uses, newest first.
+	All(ctx context.Context) ([]*types.PromoCode, error)
+}
+
+type SaleRepository interface {
+	// Create stores sale with its positions and decrements products stock,
+	// either everything is applied or nothing. Sale.PromoCode, when given, is
+	// applied to prices effective at the moment with types.PromoCode.Apply.
+	// Sale.Payments, when given, must cover the total, otherwise it fails with
+	// types.ErrPaymentShort. Quantity the customer had reserved can be bought
+	// and is taken out of their reservations.
+	Create(ctx context.Context, sale *types.Sale) (*types.Sale, error)
+	// Pay records payments of the sale with types.Sale.Pay.
+	Pay(ctx context.Context, saleID int64, payments []*types.Payment) (*types.Sale, error)
+	// Void cancels the sale without payments and puts its not returned quantity
+	// back in stock.
+	Void(ctx context.Context, saleID, managerID int64) (*types.Sale, error)
+	// TotalByManager returns net totals of the manager's sales which aren't voided,
+	// one per currency.
+	TotalByManager(ctx context.Context, managerID int64) ([]types.Money, error)
+	// ByID returns sale with positions, product names, customer and payments.
+	ByID(ctx context.Context, id int64) (*types.Sale, error)
+	List(ctx context.Context, filter *types.SaleFilter) (*types.SalePage, error)
+	// AssignManager sets manager of the sale made without one,
+	// fails with types.ErrSaleAssigned when the sale already has a manager.
+	AssignManager(ctx context.Context, saleID, managerID int64) (*types.Sale, error)
+}
+
+type CartRepository interface {
+	// ByCustomer returns cart with current product names and prices, empty when nothing was added.
+	ByCustomer(ctx context.Context, customerID int64) (*types.Cart, error)
+	// SetItem adds product to cart or replaces its qty.
+	SetItem(ctx context.Context, customerID, productID int64, qty int) error
+	RemoveItem(ctx context.Context, customerID, productID int64) error
+	Clear(ctx context.Context, customerID int64) error
+}
+
+type ReservationRepository interface {
+	// Create holds positions until now + ttl, fails with types.ErrNotEnoughQty
+	// when available quantity of any product is not enough.
+	Create(ctx context.Context, reservation *types.Reservation, ttl time.Duration) (*types.Reservation, error)
+	ByID(ctx context.Context, id int64) (*types.Reservation, error)
+	Delete(ctx context.Context, id int64) error
+	// Confirm turns reservation into a sale at current prices in one transaction,
+	// fails with types.ErrReservationExpired when reservation is expired.
+	Confirm(ctx context.Context, id, managerID int64) (*types.Sale, error)
+	// DeleteExpired releases expired reservations and returns their count.
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type ReturnRepository interface {
+	// Create stores return and puts products back to stock. Positions without
+	// SalePositionID are not allowed, empty positions mean "everything not returned yet".
+	// Fails with types.ErrReturnExceedsQty when more than sold would be returned.
+	Create(ctx context.Context, ret *types.Return) (*types.Return, error)
+	BySale(ctx context.Context, saleID int64) ([]*types.Return, error)
+}
+
+type TokenRepository interface {
+	Save(ctx context.Context, principal Principal, token string, id int64, ttl time.Duration) error
+	// Find returns id of token owner and whether token is already expired.
+	Find(ctx context.Context, principal Principal, token string) (id int64, expired bool, err error)
+	Prolong(ctx context.Context, principal Principal, token string, ttl time.Duration) error
+}
@@ -0,0 +1,144 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/bdaler/crud/pkg/types"
+	"net/http"
+	"strings"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+type ErrorResponse struct {
+	Status    int                `json:"status"`
+	Code      string             `json:"code"`
+	Message   string             `json:"message"`
+	Fields    []types.FieldError `json:"fields,omitempty"`
+	RequestID string             `json:"request_id,omitempty"`
+}
+
+type mapping struct {
+	err    error
+	status int
+	code   string
+}
+
+var mappings = []mapping{
+	{types.ErrNotFound, http.StatusNotFound, "not_found"},
+	{types.ErrInternal, http.StatusInternalServerError, "internal_error"},
+	{types.ErrNoAuthentication, http.StatusUnauthorized, "no_authentication"},
+	{types.ErrTokenNotFound, http.StatusUnauthorized, "token_not_found"},
+	{types.ErrTokenExpired, http.StatusUnauthorized, "token_expired"},
+	{types.ErrNoSuchUser, http.StatusUnauthorized, "no_such_user"},
+	{types.ErrInvalidPassword, http.StatusUnauthorized, "invalid_password"},
+	{types.ErrPhoneUsed, http.StatusConflict, "phone_used"},
+	{types.ErrInvalidPosition, http.StatusUnprocessableEntity, "invalid_position"},
+	{types.ErrNotEnoughQty, http.StatusConflict, "not_enough_qty"},
+	{types.ErrUnknownRole, http.StatusBadRequest, "unknown_role"},
+	{types.ErrProductInUse, http.StatusConflict, "product_in_use"},
+	{types.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+	{types.ErrInvalidSort, http.StatusBadRequest, "invalid_sort"},
+	{types.ErrReturnExceedsQty, http.StatusConflict, "return_exceeds_qty"},
+	{types.ErrCartEmpty, http.StatusUnprocessableEntity, "cart_empty"},
+	{types.ErrSaleAssigned, http.StatusConflict, "sale_assigned"},
+	{types.ErrReservationExpired, http.StatusGone, "reservation_expired"},
+	{types.ErrCategoryNotEmpty, http.StatusConflict, "category_not_empty"},
+	{types.ErrCategoryCycle, http.StatusUnprocessableEntity, "category_cycle"},
+	{types.ErrPriceInEffect, http.StatusConflict, "price_in_effect"},
+	{types.ErrPromoInvalid, http.StatusUnprocessableEntity, "promo_invalid"},
+	{types.ErrPromoCodeUsed, http.StatusConflict, "promo_code_used"},
+	{types.ErrPromoNotApplicable, http.StatusUnprocessableEntity, "promo_not_applicable"},
+	{types.ErrPromoExhausted, http.StatusConflict, "promo_exhausted"},
+	{types.ErrCurrencyMismatch, http.StatusUnprocessableEntity, "currency_mismatch"},
+	{types.ErrMoneyOverflow, http.StatusUnprocessableEntity, "amount_overflow"},
+	{types.ErrPaymentShort, http.StatusUnprocessableEntity, "payment_short"},
+	{types.ErrOverpayment, http.StatusUnprocessableEntity, "overpayment"},
+	{types.ErrSalePaid, http.StatusConflict, "sale_paid"},
+	{types.ErrSaleVoided, http.StatusConflict, "sale_voided"},
+	{types.ErrSaleHasPayments, http.StatusConflict, "sale_has_payments"},
+}
+
+// Status maps sentinel errors from pkg/types to http status codes,
+// anything unknown is treated as internal error.
+func Status(err error) int {
+	var validationErr *types.ValidationError
+	if errors.As(err, &validationErr) {
+		return http.StatusBadRequest
+	}
+
+	for _, m := range mappings {
+		if errors.Is(err, m.err) {
+			return m.status
+		}
+	}
+
+	return http.StatusInternalServerError
+}
+
+func WriteError(w http.ResponseWriter, status int, err error) {
+	// set on every response by the request id middleware
+	requestID := w.Header().Get(RequestIDHeader)
+	if status >= http.StatusInternalServerError {
+		logger.Errorf("%d %v %s", status, err, requestID)
+	} else {
+		logger.Debugf("%d %v %s", status, err, requestID)
+	}
+
+	resp := &ErrorResponse{
+		Status:    status,
+		Code:      codeOf(status, err),
+		Message:   http.StatusText(status),
+		RequestID: requestID,
+	}
+
+	var validationErr *types.ValidationError
+	if errors.As(err, &validationErr) {
+		resp.Fields = validationErr.Fields
+	}
+
+	// don't leak details of unexpected errors to clients
+	if err != nil && (status < http.StatusInternalServerError || isKnown(err)) {
+		resp.Message = err.Error()
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		logger.Error(err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_, err = w.Write(data)
+	if err != nil {
+		logger.Errorf("response write error: %v", err)
+	}
+}
+
+func codeOf(status int, err error) string {
+	var validationErr *types.ValidationError
+	if errors.As(err, &validationErr) {
+		return "validation_error"
+	}
+
+	for _, m := range mappings {
+		if errors.Is(err, m.err) {
+			return m.code
+		}
+	}
+
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+func isKnown(err error) bool {
+	for _, m := range mappings {
+		if errors.Is(err, m.err) {
+			return true
+		}
+	}
+	return false
+}
@@ -5,10 +5,11 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"github.com/bdaler/crud/pkg/logger"
+	"github.com/bdaler/crud/pkg/types"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"golang.org/x/crypto/bcrypt"
-	"log"
 	"time"
 )
 
@@ -16,7 +17,7 @@ var (
 	ErrNoSuchUser      = errors.New("no such user")
 	ErrInvalidPassword = errors.New("invalid password")
 	ErrInternal        = errors.New("internal error")
-	ErrExpireToken     = errors.New("token expired")
+	ErrExpireToken     = types.ErrTokenExpired
 )
 
 type Service struct {
@@ -36,7 +37,7 @@ func (s *Service) Auth(login, password string) bool {
 		password).Scan(&login, &password)
 
 	if err != nil {
-		log.Print(err)
+		logger.Error(err)
 		return false
 	}
 	return true
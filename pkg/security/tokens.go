@@ -0,0 +1,75 @@
+package security
+
+import (
+	"context"
+	"github.com/bdaler/crud/pkg/repository"
+	"github.com/bdaler/crud/pkg/types"
+	"github.com/bdaler/crud/pkg/utils"
+	"time"
+)
+
+const DefaultTokenTTL = time.Hour
+
+// TokenOptions controls lifetime of issued tokens.
+// With Sliding enabled every successful lookup moves expire forward by TTL.
+type TokenOptions struct {
+	TTL     time.Duration
+	Sliding bool
+}
+
+func NewTokenOptions() *TokenOptions {
+	return &TokenOptions{TTL: DefaultTokenTTL}
+}
+
+// Tokens issues and checks tokens of one principal (customers or managers).
+type Tokens struct {
+	repo      repository.TokenRepository
+	principal repository.Principal
+	opts      *TokenOptions
+}
+
+func NewTokens(repo repository.TokenRepository, principal repository.Principal, opts *TokenOptions) *Tokens {
+	if opts == nil {
+		opts = NewTokenOptions()
+	}
+	return &Tokens{repo: repo, principal: principal, opts: opts}
+}
+
+func (t *Tokens) Issue(ctx context.Context, id int64) (string, error) {
+	token, err := utils.GenerateTokenStr()
+	if err != nil {
+		return "", err
+	}
+
+	err = t.repo.Save(ctx, t.principal, token, id, t.opts.TTL)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// IDByToken returns 0 without error for unknown tokens so that anonymous
+// requests can pass through, and types.ErrTokenExpired for expired ones.
+func (t *Tokens) IDByToken(ctx context.Context, token string) (int64, error) {
+	id, expired, err := t.repo.Find(ctx, t.principal, token)
+	if err == types.ErrTokenNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if expired {
+		return 0, types.ErrTokenExpired
+	}
+
+	if t.opts.Sliding {
+		err = t.repo.Prolong(ctx, t.principal, token, t.opts.TTL)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return id, nil
+}
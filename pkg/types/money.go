@@ -0,0 +1,176 @@
+package types
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in minor units (dirams, cents) of ISO 4217 Currency.
+// Zero value means "nothing yet", it can be added to money of any currency.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// minorUnits lists currencies whose minor unit is not 1/100.
+var minorUnits = map[string]int{
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ValidCurrency reports whether code looks like ISO 4217 alphabetic code.
+func ValidCurrency(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// UnmarshalJSON accepts bare number as well, currency is left empty then
+// for the service to fill in the default one.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if trimmed := strings.TrimSpace(string(data)); trimmed != "" && trimmed[0] != '{' {
+		var amount int64
+		if err := json.Unmarshal(data, &amount); err != nil {
+			return err
+		}
+		*m = Money{Amount: amount}
+		return nil
+	}
+
+	type plain Money
+	var value plain
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*m = Money(value)
+	return nil
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// currency returns the common currency of m and o, zero money takes any.
+func (m Money) currency(o Money) (string, error) {
+	switch {
+	case m.Currency == o.Currency:
+		return m.Currency, nil
+	case m.Currency == "" && m.Amount == 0:
+		return o.Currency, nil
+	case o.Currency == "" && o.Amount == 0:
+		return m.Currency, nil
+	}
+	return "", ErrCurrencyMismatch
+}
+
+func (m Money) Add(o Money) (Money, error) {
+	currency, err := m.currency(o)
+	if err != nil {
+		return Money{}, err
+	}
+	if (o.Amount > 0 && m.Amount > math.MaxInt64-o.Amount) || (o.Amount < 0 && m.Amount < math.MinInt64-o.Amount) {
+		return Money{}, ErrMoneyOverflow
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: currency}, nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	if o.Amount == math.MinInt64 {
+		return Money{}, ErrMoneyOverflow
+	}
+	return m.Add(Money{Amount: -o.Amount, Currency: o.Currency})
+}
+
+func (m Money) Mul(n int64) (Money, error) {
+	return m.MulDiv(n, 1)
+}
+
+// MulDiv returns m * num / den truncated toward zero, the product doesn't overflow.
+func (m Money) MulDiv(num, den int64) (Money, error) {
+	return m.mulDiv(num, den, false)
+}
+
+// MulDivRound returns m * num / den rounded half away from zero.
+func (m Money) MulDivRound(num, den int64) (Money, error) {
+	return m.mulDiv(num, den, true)
+}
+
+func (m Money) mulDiv(num, den int64, round bool) (Money, error) {
+	if den == 0 {
+		return Money{}, ErrMoneyOverflow
+	}
+	value := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(num))
+	divisor := big.NewInt(den)
+	quotient, remainder := new(big.Int).QuoRem(value, divisor, new(big.Int))
+	if round && new(big.Int).Abs(new(big.Int).Lsh(remainder, 1)).Cmp(new(big.Int).Abs(divisor)) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(value.Sign()*divisor.Sign())))
+	}
+	if !quotient.IsInt64() {
+		return Money{}, ErrMoneyOverflow
+	}
+	return Money{Amount: quotient.Int64(), Currency: m.Currency}, nil
+}
+
+// Cmp compares amounts of money in the same currency.
+func (m Money) Cmp(o Money) (int, error) {
+	if _, err := m.currency(o); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.Amount < o.Amount:
+		return -1, nil
+	case m.Amount > o.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// String formats money in major units, e.g. "12.50 TJS".
+func (m Money) String() string {
+	digits, ok := minorUnits[m.Currency]
+	if !ok {
+		digits = 2
+	}
+
+	sign := ""
+	amount := strconv.FormatInt(m.Amount, 10)
+	if strings.HasPrefix(amount, "-") {
+		sign, amount = "-", amount[1:]
+	}
+	if digits > 0 {
+		if len(amount) <= digits {
+			amount = strings.Repeat("0", digits-len(amount)+1) + amount
+		}
+		amount = amount[:len(amount)-digits] + "." + amount[len(amount)-digits:]
+	}
+	return strings.TrimSpace(sign + amount + " " + m.Currency)
+}
+
+// AddByCurrency adds money to totals kept one per currency.
+func AddByCurrency(totals []Money, m Money) ([]Money, error) {
+	for i, total := range totals {
+		if total.Currency == m.Currency {
+			sum, err := total.Add(m)
+			if err != nil {
+				return nil, err
+			}
+			totals[i] = sum
+			return totals, nil
+		}
+	}
+	return append(totals, m), nil
+}
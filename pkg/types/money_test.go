@@ -0,0 +1,216 @@
+package types
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestMoney_Add(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Money
+		want Money
+		err  error
+	}{
+		{"same currency", NewMoney(150, "TJS"), NewMoney(250, "TJS"), NewMoney(400, "TJS"), nil},
+		{"zero takes currency", Money{}, NewMoney(250, "USD"), NewMoney(250, "USD"), nil},
+		{"adds to zero", NewMoney(250, "USD"), Money{}, NewMoney(250, "USD"), nil},
+		{"mismatch", NewMoney(1, "TJS"), NewMoney(1, "USD"), Money{}, ErrCurrencyMismatch},
+		{"non-zero without currency", NewMoney(1, "TJS"), NewMoney(1, ""), Money{}, ErrCurrencyMismatch},
+		{"overflow", NewMoney(math.MaxInt64, "TJS"), NewMoney(1, "TJS"), Money{}, ErrMoneyOverflow},
+		{"negative overflow", NewMoney(math.MinInt64, "TJS"), NewMoney(-1, "TJS"), Money{}, ErrMoneyOverflow},
+		{"max", NewMoney(math.MaxInt64-1, "TJS"), NewMoney(1, "TJS"), NewMoney(math.MaxInt64, "TJS"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.a.Add(tt.b)
+			if err != tt.err {
+				t.Fatalf("Add() error = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("Add() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoney_Sub(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Money
+		want Money
+		err  error
+	}{
+		{"same currency", NewMoney(400, "TJS"), NewMoney(150, "TJS"), NewMoney(250, "TJS"), nil},
+		{"below zero", NewMoney(100, "TJS"), NewMoney(150, "TJS"), NewMoney(-50, "TJS"), nil},
+		{"mismatch", NewMoney(1, "TJS"), NewMoney(1, "EUR"), Money{}, ErrCurrencyMismatch},
+		{"min int", NewMoney(0, "TJS"), NewMoney(math.MinInt64, "TJS"), Money{}, ErrMoneyOverflow},
+		{"overflow", NewMoney(math.MinInt64, "TJS"), NewMoney(1, "TJS"), Money{}, ErrMoneyOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.a.Sub(tt.b)
+			if err != tt.err {
+				t.Fatalf("Sub() error = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("Sub() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoney_Mul(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Money
+		n    int64
+		want Money
+		err  error
+	}{
+		{"qty", NewMoney(1250, "TJS"), 3, NewMoney(3750, "TJS"), nil},
+		{"zero", NewMoney(1250, "TJS"), 0, NewMoney(0, "TJS"), nil},
+		{"overflow", NewMoney(math.MaxInt64/2+1, "TJS"), 2, Money{}, ErrMoneyOverflow},
+		{"negative overflow", NewMoney(math.MinInt64, "TJS"), -1, Money{}, ErrMoneyOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.m.Mul(tt.n)
+			if err != tt.err {
+				t.Fatalf("Mul() error = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("Mul() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoney_MulDiv(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        Money
+		num, den int64
+		want     int64
+		round    int64
+		err      error
+	}{
+		{"exact", NewMoney(1000, "TJS"), 15, 100, 150, 150, nil},
+		{"below half", NewMoney(1000, "TJS"), 1, 3, 333, 333, nil},
+		{"rounds up", NewMoney(1001, "TJS"), 1, 3, 333, 334, nil},
+		{"half", NewMoney(25, "TJS"), 1, 10, 2, 3, nil},
+		{"above half", NewMoney(2, "TJS"), 2, 3, 1, 1, nil},
+		{"negative half", NewMoney(-25, "TJS"), 1, 10, -2, -3, nil},
+		{"inclusive tax", NewMoney(1200, "TJS"), 2000, 12000, 200, 200, nil},
+		{"product exceeds int64", NewMoney(math.MaxInt64, "TJS"), 10, 20, math.MaxInt64 / 2, math.MaxInt64/2 + 1, nil},
+		{"overflow", NewMoney(math.MaxInt64, "TJS"), 3, 2, 0, 0, ErrMoneyOverflow},
+		{"zero divisor", NewMoney(1, "TJS"), 1, 0, 0, 0, ErrMoneyOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.m.MulDiv(tt.num, tt.den)
+			if err != tt.err {
+				t.Fatalf("MulDiv() error = %v, want %v", err, tt.err)
+			}
+			if got.Amount != tt.want {
+				t.Errorf("MulDiv() = %d, want %d", got.Amount, tt.want)
+			}
+
+			got, err = tt.m.MulDivRound(tt.num, tt.den)
+			if err != tt.err {
+				t.Fatalf("MulDivRound() error = %v, want %v", err, tt.err)
+			}
+			if got.Amount != tt.round {
+				t.Errorf("MulDivRound() = %d, want %d", got.Amount, tt.round)
+			}
+			if err == nil && got.Currency != tt.m.Currency {
+				t.Errorf("MulDivRound() currency = %q, want %q", got.Currency, tt.m.Currency)
+			}
+		})
+	}
+}
+
+func TestMoney_Cmp(t *testing.T) {
+	if got, err := NewMoney(1, "TJS").Cmp(NewMoney(2, "TJS")); err != nil || got != -1 {
+		t.Errorf("Cmp() = %d, %v, want -1", got, err)
+	}
+	if _, err := NewMoney(1, "TJS").Cmp(NewMoney(1, "USD")); err != ErrCurrencyMismatch {
+		t.Errorf("Cmp() error = %v, want %v", err, ErrCurrencyMismatch)
+	}
+}
+
+func TestMoney_String(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{NewMoney(1250, "TJS"), "12.50 TJS"},
+		{NewMoney(5, "TJS"), "0.05 TJS"},
+		{NewMoney(0, "USD"), "0.00 USD"},
+		{NewMoney(-1250, "TJS"), "-12.50 TJS"},
+		{NewMoney(-5, "EUR"), "-0.05 EUR"},
+		{NewMoney(1500, "JPY"), "1500 JPY"},
+		{NewMoney(1500, "KWD"), "1.500 KWD"},
+		{NewMoney(1250, ""), "12.50"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.m.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoney_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		data string
+		want Money
+	}{
+		{`1250`, NewMoney(1250, "")},
+		{`{"amount":1250,"currency":"USD"}`, NewMoney(1250, "USD")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			var got Money
+			if err := json.Unmarshal([]byte(tt.data), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("UnmarshalJSON() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var m Money
+	if err := json.Unmarshal([]byte(`"12.50"`), &m); err == nil {
+		t.Error("UnmarshalJSON() accepted string amount")
+	}
+}
+
+func TestAddByCurrency(t *testing.T) {
+	totals := make([]Money, 0)
+	for _, m := range []Money{NewMoney(100, "TJS"), NewMoney(5, "USD"), NewMoney(50, "TJS")} {
+		var err error
+		if totals, err = AddByCurrency(totals, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []Money{NewMoney(150, "TJS"), NewMoney(5, "USD")}
+	if len(totals) != len(want) || totals[0] != want[0] || totals[1] != want[1] {
+		t.Errorf("AddByCurrency() = %v, want %v", totals, want)
+	}
+
+	if _, err := AddByCurrency([]Money{NewMoney(math.MaxInt64, "TJS")}, NewMoney(1, "TJS")); err != ErrMoneyOverflow {
+		t.Errorf("AddByCurrency() error = %v, want %v", err, ErrMoneyOverflow)
+	}
+}
+
+func TestValidCurrency(t *testing.T) {
+	for code, want := range map[string]bool{"TJS": true, "usd": false, "US": false, "USDT": false, "U$D": false} {
+		if got := ValidCurrency(code); got != want {
+			t.Errorf("ValidCurrency(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
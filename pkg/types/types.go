@@ -2,17 +2,79 @@ package types
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
 var (
-	ErrNotFound        = errors.New("item not found")
-	ErrInternal        = errors.New("internal error")
-	ErrTokenNotFound   = errors.New("token not found")
-	ErrNoSuchUser      = errors.New("no such user")
-	ErrInvalidPassword = errors.New("invalid password")
-	ErrPhoneUsed       = errors.New("phone alredy registered")
-	ErrTokenExpired    = errors.New("token expired")
+	ErrNotFound           = errors.New("item not found")
+	ErrInternal           = errors.New("internal error")
+	ErrTokenNotFound      = errors.New("token not found")
+	ErrNoSuchUser         = errors.New("no such user")
+	ErrInvalidPassword    = errors.New("invalid password")
+	ErrPhoneUsed          = errors.New("phone alredy registered")
+	ErrTokenExpired       = errors.New("token expired")
+	ErrInvalidPosition    = errors.New("invalid sale position")
+	ErrNotEnoughQty       = errors.New("not enough quantity")
+	ErrUnknownRole        = errors.New("unknown role")
+	ErrNoAuthentication   = errors.New("no authentication")
+	ErrProductInUse       = errors.New("product is referenced by sales")
+	ErrInvalidCursor      = errors.New("invalid cursor")
+	ErrInvalidSort        = errors.New("invalid sort")
+	ErrReturnExceedsQty   = errors.New("return exceeds sold quantity")
+	ErrCartEmpty          = errors.New("cart is empty")
+	ErrSaleAssigned       = errors.New("sale already has manager")
+	ErrReservationExpired = errors.New("reservation expired")
+	ErrCategoryNotEmpty   = errors.New("category has subcategories")
+	ErrCategoryCycle      = errors.New("category can't be nested into itself")
+	ErrPriceInEffect      = errors.New("price is already in effect")
+	ErrPromoInvalid       = errors.New("promo code is not valid")
+	ErrPromoCodeUsed      = errors.New("promo code already exists")
+	ErrPromoNotApplicable = errors.New("promo code is not applicable to the sale")
+	ErrPromoExhausted     = errors.New("promo code usage limit reached")
+	ErrCurrencyMismatch   = errors.New("currencies don't match")
+	ErrMoneyOverflow      = errors.New("amount is out of range")
+	ErrPaymentShort       = errors.New("payments don't cover the total")
+	ErrOverpayment        = errors.New("only cash can exceed the amount due")
+	ErrSalePaid           = errors.New("sale is paid already")
+	ErrSaleVoided         = errors.New("sale is voided")
+	ErrSaleHasPayments    = errors.New("sale has payments")
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// Err returns nil when no field errors were collected, so that callers can write
+// `if err := v.Err(); err != nil`.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Field+": "+field.Message)
+	}
+	return "validation failed: " + strings.Join(messages, ", ")
+}
+
+const (
+	RoleManager = "MANAGER"
+	RoleAdmin   = "ADMIN"
 )
 
 type Manager struct {
@@ -24,40 +86,687 @@ type Manager struct {
 	Department string    `json:"department"`
 	Phone      string    `json:"phone"`
 	Password   string    `json:"password"`
-	IsAdmin    bool      `json:"is_admin"`
+	Roles      []string  `json:"roles"`
 	Created    time.Time `json:"created"`
 }
 
+// Product.Available is Qty minus quantity held by active reservations.
+// Product is low on stock when Qty is at or below non-zero ReorderLevel.
 type Product struct {
-	ID      int64     `json:"id"`
-	Name    string    `json:"name"`
-	Price   int       `json:"price"`
-	Qty     int       `json:"qty"`
-	Active  bool      `json:"active"`
-	Created time.Time `json:"created"`
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Price        Money     `json:"price"`
+	Qty          int       `json:"qty"`
+	Available    int       `json:"available"`
+	ReorderLevel int       `json:"reorder_level"`
+	Active       bool      `json:"active"`
+	Created      time.Time `json:"created"`
+}
+
+func (p *Product) LowStock() bool {
+	return p.ReorderLevel > 0 && p.Qty <= p.ReorderLevel
 }
 
+// Category with zero ParentID is a root one. TaxRate is in basis points
+// (1800 is 18%), nil means the rate and mode of the parent category apply.
+// TaxInclusive tells that prices of products already include the tax.
+type Category struct {
+	ID           int64       `json:"id"`
+	ParentID     int64       `json:"parent_id"`
+	Name         string      `json:"name"`
+	TaxRate      *int        `json:"tax_rate"`
+	TaxInclusive bool        `json:"tax_inclusive"`
+	Created      time.Time   `json:"created"`
+	Children     []*Category `json:"children,omitempty"`
+}
+
+// CategoryTree nests flat list of categories into their parents
+// and returns roots, order of items is kept.
+func CategoryTree(items []*Category) []*Category {
+	byID := make(map[int64]*Category, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+
+	roots := make([]*Category, 0)
+	for _, item := range items {
+		parent, ok := byID[item.ParentID]
+		if !ok {
+			roots = append(roots, item)
+			continue
+		}
+		parent.Children = append(parent.Children, item)
+	}
+	return roots
+}
+
+// Cart holds products customer is going to buy, prices are the current ones.
+// Totals has an entry per currency of the products.
+type Cart struct {
+	CustomerID int64       `json:"customer_id"`
+	Items      []*CartItem `json:"items"`
+	Totals     []Money     `json:"totals"`
+}
+
+func (c *Cart) CalcTotals() error {
+	c.Totals = make([]Money, 0, 1)
+	for _, item := range c.Items {
+		total, err := item.Price.Mul(int64(item.Qty))
+		if err != nil {
+			return err
+		}
+		item.Total = total
+		if c.Totals, err = AddByCurrency(c.Totals, total); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type CartItem struct {
+	ProductID   int64     `json:"product_id"`
+	ProductName string    `json:"product_name"`
+	Price       Money     `json:"price"`
+	Qty         int       `json:"qty"`
+	Total       Money     `json:"total"`
+	Created     time.Time `json:"created"`
+}
+
+const (
+	SalePending       = "pending"
+	SalePartiallyPaid = "partially_paid"
+	SalePaid          = "paid"
+	SaleVoided        = "voided"
+)
+
+// Sale made by customer via checkout has zero ManagerID until manager is assigned.
+// Total is net of Discount and includes Tax, which are the sums over positions.
+// All amounts are in Currency, positions priced in different currencies can't
+// make a sale. Due is what is left to pay after returns and payments, Status
+// follows from it unless the sale is voided, so a sale with nothing to pay
+// (e.g. fully discounted) is paid when created. LowStock lists products which
+// the sale dropped to or below their reorder level, it's only filled on create.
 type Sale struct {
-	ID         int64           `json:"id"`
-	ManagerID  int64           `json:"manager_id"`
-	CustomerID int64           `json:"customer_id"`
-	Created    time.Time       `json:"created"`
-	Positions  []*SalePosition `json:"positions"`
+	ID          int64           `json:"id"`
+	ManagerID   int64           `json:"manager_id"`
+	CustomerID  int64           `json:"customer_id"`
+	Customer    *Customer       `json:"customer,omitempty"`
+	PromoCodeID int64           `json:"promo_code_id,omitempty"`
+	PromoCode   string          `json:"promo_code,omitempty"`
+	Status      string          `json:"status"`
+	Currency    string          `json:"currency"`
+	Discount    Money           `json:"discount"`
+	Tax         Money           `json:"tax"`
+	Total       Money           `json:"total"`
+	Returned    Money           `json:"returned"`
+	Paid        Money           `json:"paid"`
+	Due         Money           `json:"due"`
+	Voided      *time.Time      `json:"voided,omitempty"`
+	Created     time.Time       `json:"created"`
+	Positions   []*SalePosition `json:"positions"`
+	Payments    []*Payment      `json:"payments"`
+	LowStock    []*Product      `json:"-"`
 }
 
-type SalePosition struct {
+// CalcTotals fills currency of the sale, line totals of positions, total of the sale,
+// its discount, tax and sum of returns. Fails with ErrCurrencyMismatch when positions
+// are priced in different currencies and with ErrMoneyOverflow.
+func (s *Sale) CalcTotals() error {
+	if len(s.Positions) != 0 {
+		s.Currency = s.Positions[0].Price.Currency
+	}
+	zero := Money{Currency: s.Currency}
+	s.Total, s.Discount, s.Tax, s.Returned = zero, zero, zero, zero
+
+	add := func(sum *Money, value Money) (err error) {
+		*sum, err = sum.Add(value)
+		return err
+	}
+	for _, position := range s.Positions {
+		if position.Price.Currency != s.Currency {
+			return ErrCurrencyMismatch
+		}
+		if position.Discount.IsZero() {
+			position.Discount.Currency = s.Currency
+		}
+		total, err := position.Amount()
+		if err != nil {
+			return err
+		}
+		position.Total = total
+		paid, err := position.PaidPrice()
+		if err != nil {
+			return err
+		}
+		returned, err := paid.Mul(int64(position.ReturnedQty))
+		if err != nil {
+			return err
+		}
+		for _, err = range []error{
+			add(&s.Total, total),
+			add(&s.Discount, position.Discount),
+			add(&s.Tax, position.Tax),
+			add(&s.Returned, returned),
+		} {
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return s.calcDue()
+}
+
+// NoteStock takes product as it is after selling qty of it, the product goes to
+// LowStock when the sale dropped it to or below its reorder level.
+func (s *Sale) NoteStock(product *Product, qty int) {
+	for _, low := range s.LowStock {
+		if low.ID == product.ID {
+			low.Qty = product.Qty
+			return
+		}
+	}
+	if product.LowStock() && product.Qty+qty > product.ReorderLevel {
+		s.LowStock = append(s.LowStock, product)
+	}
+}
+
+// calcDue sums payments and sets Due and Status.
+func (s *Sale) calcDue() error {
+	s.Paid = Money{Currency: s.Currency}
+	for _, payment := range s.Payments {
+		var err error
+		if s.Paid, err = s.Paid.Add(payment.Amount); err != nil {
+			return err
+		}
+	}
+
+	due, err := s.Total.Sub(s.Returned)
+	if err == nil {
+		due, err = due.Sub(s.Paid)
+	}
+	if err != nil {
+		return err
+	}
+	if due.Amount < 0 {
+		due.Amount = 0
+	}
+	s.Due = due
+
+	switch {
+	case s.Voided != nil:
+		s.Status = SaleVoided
+	case s.Due.IsZero():
+		s.Status = SalePaid
+	case !s.Paid.IsZero():
+		s.Status = SalePartiallyPaid
+	default:
+		s.Status = SalePending
+	}
+	return nil
+}
+
+// Pay applies tenders to the amount due and appends them to Payments. Tender
+// Amount is what the customer hands over: non-cash tenders are applied first and
+// must not exceed what's due, cash goes last and its excess is given back as
+// Change. Amounts without currency are in the currency of the sale. Tenders left
+// over when nothing is due, and any tender for a sale with nothing to pay, are
+// validation errors.
+func (s *Sale) Pay(payments []*Payment) error {
+	switch {
+	case s.Status == SaleVoided:
+		return ErrSaleVoided
+	case s.Due.IsZero() && s.Paid.IsZero():
+		validation := &ValidationError{}
+		validation.Add("payments", "sale has nothing to pay")
+		return validation
+	case s.Status == SalePaid:
+		return ErrSalePaid
+	}
+
+	// indexes of payments, non-cash first
+	ordered := make([]int, 0, len(payments))
+	for i, payment := range payments {
+		if payment.Tender != TenderCash {
+			ordered = append(ordered, i)
+		}
+	}
+	for i, payment := range payments {
+		if payment.Tender == TenderCash {
+			ordered = append(ordered, i)
+		}
+	}
+
+	due := s.Due
+	for _, i := range ordered {
+		payment := payments[i]
+		if due.IsZero() {
+			validation := &ValidationError{}
+			validation.Add(fmt.Sprintf("payments[%d]", i), "nothing left to pay")
+			return validation
+		}
+		if payment.Amount.Currency == "" {
+			payment.Amount.Currency = s.Currency
+		}
+		if payment.Amount.Currency != s.Currency {
+			return ErrCurrencyMismatch
+		}
+		payment.Tendered = payment.Amount
+		payment.Change = Money{Currency: s.Currency}
+		if payment.Amount.Amount > due.Amount {
+			if payment.Tender != TenderCash {
+				return ErrOverpayment
+			}
+			payment.Amount = due
+			payment.Change = NewMoney(payment.Tendered.Amount-due.Amount, s.Currency)
+		}
+		due.Amount -= payment.Amount.Amount
+	}
+
+	s.Payments = append(s.Payments, payments...)
+	return s.calcDue()
+}
+
+// MaxTaxRate is 100% in basis points.
+const MaxTaxRate = 10000
+
+// TaxRule is the tax of a product resolved from its categories.
+type TaxRule struct {
+	Rate      int
+	Inclusive bool
+}
+
+// ApplyTax fills tax of positions from rules by product id, products without
+// a rule are not taxed. Tax is calculated on the line total after discount:
+// on top of it in exclusive mode, as its part in inclusive mode, rounded half up.
+func (s *Sale) ApplyTax(rules map[int64]TaxRule) error {
+	for _, position := range s.Positions {
+		rule := rules[position.ProductID]
+		position.TaxRate = rule.Rate
+		position.TaxInclusive = rule.Inclusive
+
+		amount, err := position.Price.Mul(int64(position.Qty))
+		if err == nil {
+			amount, err = amount.Sub(position.Discount)
+		}
+		if err != nil {
+			return err
+		}
+		if rule.Inclusive {
+			position.Tax, err = amount.MulDivRound(int64(rule.Rate), int64(MaxTaxRate+rule.Rate))
+		} else {
+			position.Tax, err = amount.MulDivRound(int64(rule.Rate), MaxTaxRate)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const (
+	TenderCash        = "cash"
+	TenderCard        = "card"
+	TenderTransfer    = "transfer"
+	TenderStoreCredit = "store_credit"
+)
+
+// Payment is a tender applied to the sale. Amount is the part of the sale it
+// covers, Tendered is what was handed over and Change is given back, only cash
+// gets change. Reference identifies card authorization or transfer.
+type Payment struct {
 	ID        int64     `json:"id"`
-	ProductID int64     `json:"product_id"`
 	SaleID    int64     `json:"sale_id"`
-	Price     int       `json:"price"`
-	Qty       int       `json:"qty"`
+	Tender    string    `json:"tender"`
+	Amount    Money     `json:"amount"`
+	Tendered  Money     `json:"tendered"`
+	Change    Money     `json:"change"`
+	Reference string    `json:"reference"`
+	ManagerID int64     `json:"manager_id"`
 	Created   time.Time `json:"created"`
 }
 
+type SalePosition struct {
+	ID           int64     `json:"id"`
+	ProductID    int64     `json:"product_id"`
+	ProductName  string    `json:"product_name,omitempty"`
+	SaleID       int64     `json:"sale_id"`
+	Price        Money     `json:"price"`
+	Qty          int       `json:"qty"`
+	Discount     Money     `json:"discount"`
+	TaxRate      int       `json:"tax_rate"`
+	TaxInclusive bool      `json:"tax_inclusive"`
+	Tax          Money     `json:"tax"`
+	ReturnedQty  int       `json:"returned_qty"`
+	Total        Money     `json:"total"`
+	Created      time.Time `json:"created"`
+}
+
+// Amount is the line total after discount with exclusive tax added.
+func (p *SalePosition) Amount() (Money, error) {
+	amount, err := p.Price.Mul(int64(p.Qty))
+	if err == nil {
+		amount, err = amount.Sub(p.Discount)
+	}
+	if err == nil && !p.TaxInclusive {
+		amount, err = amount.Add(p.Tax)
+	}
+	return amount, err
+}
+
+// PaidPrice is the unit price actually paid, it's what returns refund. Rounded
+// down so that returning everything never refunds more than was paid.
+func (p *SalePosition) PaidPrice() (Money, error) {
+	if p.Qty == 0 {
+		return p.Price, nil
+	}
+	amount, err := p.Amount()
+	if err != nil {
+		return Money{}, err
+	}
+	return amount.MulDiv(1, int64(p.Qty))
+}
+
+const (
+	PromoPercent = "percent"
+	PromoFixed   = "fixed"
+)
+
+// PromoCode gives Value percent or Value of money off. With non-zero ProductID
+// only positions of that product are discounted (fixed value is per unit),
+// otherwise the whole sale. Fixed Value and MinTotal are minor units of Currency,
+// sales in other currencies can't use such codes. Zero MinTotal, MaxUses and
+// MaxUsesPerCustomer mean "no limit", nil ValidTo means "until deactivated".
+type PromoCode struct {
+	ID                 int64      `json:"id"`
+	Code               string     `json:"code"`
+	Kind               string     `json:"kind"`
+	Value              int64      `json:"value"`
+	Currency           string     `json:"currency"`
+	ProductID          int64      `json:"product_id"`
+	MinTotal           int64      `json:"min_total"`
+	ValidFrom          time.Time  `json:"valid_from"`
+	ValidTo            *time.Time `json:"valid_to"`
+	MaxUses            int        `json:"max_uses"`
+	MaxUsesPerCustomer int        `json:"max_uses_per_customer"`
+	Uses               int        `json:"uses"`
+	Active             bool       `json:"active"`
+	Created            time.Time  `json:"created"`
+}
+
+// Apply checks the promo code against the sale whose prices are already set and
+// fills discounts of its positions. Uses and customerUses are counts of sales
+// made with the code so far, in total and by the customer of the sale.
+func (p *PromoCode) Apply(sale *Sale, customerUses int, at time.Time) error {
+	if !p.Active || at.Before(p.ValidFrom) || (p.ValidTo != nil && !at.Before(*p.ValidTo)) {
+		return ErrPromoInvalid
+	}
+	if p.MaxUsesPerCustomer > 0 && sale.CustomerID == 0 {
+		return ErrPromoNotApplicable
+	}
+	if (p.MaxUses > 0 && p.Uses >= p.MaxUses) ||
+		(p.MaxUsesPerCustomer > 0 && customerUses >= p.MaxUsesPerCustomer) {
+		return ErrPromoExhausted
+	}
+
+	var total, base Money
+	eligible := make([]*SalePosition, 0, len(sale.Positions))
+	for _, position := range sale.Positions {
+		position.Discount = Money{Currency: position.Price.Currency}
+		line, err := position.Price.Mul(int64(position.Qty))
+		if err == nil {
+			total, err = total.Add(line)
+		}
+		if err == nil && (p.ProductID == 0 || position.ProductID == p.ProductID) {
+			eligible = append(eligible, position)
+			base, err = base.Add(line)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if (p.Kind == PromoFixed || p.MinTotal > 0) && total.Currency != p.Currency {
+		return ErrPromoNotApplicable
+	}
+	if total.Amount < p.MinTotal || base.Amount == 0 {
+		return ErrPromoNotApplicable
+	}
+
+	discount := Money{Amount: p.Value, Currency: base.Currency}
+	var err error
+	switch {
+	case p.Kind == PromoPercent:
+		discount, err = base.MulDiv(p.Value, 100)
+	case p.ProductID != 0:
+		discount = Money{Currency: base.Currency}
+		for _, position := range eligible {
+			var value Money
+			if value, err = NewMoney(p.Value, base.Currency).Mul(int64(position.Qty)); err == nil {
+				discount, err = discount.Add(value)
+			}
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if discount.Amount > base.Amount {
+		discount = base
+	}
+
+	// split proportionally to line totals, remainder of rounding goes to the first lines
+	left := discount.Amount
+	for _, position := range eligible {
+		line, _ := position.Price.Mul(int64(position.Qty))
+		if position.Discount, err = discount.MulDiv(line.Amount, base.Amount); err != nil {
+			return err
+		}
+		left -= position.Discount.Amount
+	}
+	for _, position := range eligible {
+		if left == 0 {
+			break
+		}
+		if line, _ := position.Price.Mul(int64(position.Qty)); position.Discount.Amount < line.Amount {
+			position.Discount.Amount++
+			left--
+		}
+	}
+
+	sale.PromoCodeID = p.ID
+	sale.PromoCode = p.Code
+	return nil
+}
+
+// Reservation holds products for a pending order until Expire,
+// confirmation turns it into a sale.
+type Reservation struct {
+	ID         int64                  `json:"id"`
+	CustomerID int64                  `json:"customer_id"`
+	ManagerID  int64                  `json:"manager_id"`
+	Expire     time.Time              `json:"expire"`
+	Created    time.Time              `json:"created"`
+	Positions  []*ReservationPosition `json:"positions"`
+}
+
+type ReservationPosition struct {
+	ID            int64 `json:"id"`
+	ReservationID int64 `json:"reservation_id"`
+	ProductID     int64 `json:"product_id"`
+	Qty           int   `json:"qty"`
+}
+
+const (
+	MovementReceipt    = "receipt"
+	MovementSale       = "sale"
+	MovementReturn     = "return"
+	MovementAdjustment = "adjustment"
+	MovementWriteOff   = "write_off"
+	MovementVoid       = "void"
+)
+
+// StockMovement is a ledger entry of products.qty change, Qty is signed.
+// Zero ManagerID, CustomerID, SaleID and ReturnID mean "not related".
+type StockMovement struct {
+	ID         int64     `json:"id"`
+	ProductID  int64     `json:"product_id"`
+	Kind       string    `json:"kind"`
+	Qty        int       `json:"qty"`
+	ManagerID  int64     `json:"manager_id"`
+	CustomerID int64     `json:"customer_id"`
+	SaleID     int64     `json:"sale_id"`
+	ReturnID   int64     `json:"return_id"`
+	Reason     string    `json:"reason"`
+	Created    time.Time `json:"created"`
+}
+
+// ProductPrice is the price of product during [EffectiveFrom, EffectiveTo),
+// nil EffectiveTo means "until further notice". Intervals may overlap, the one
+// which started last wins, so a promotion overrides the regular price for its period.
+type ProductPrice struct {
+	ID            int64      `json:"id"`
+	ProductID     int64      `json:"product_id"`
+	Price         Money      `json:"price"`
+	EffectiveFrom time.Time  `json:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to"`
+	ManagerID     int64      `json:"manager_id"`
+	Created       time.Time  `json:"created"`
+}
+
+// MovementFilter describes a page of product movements from newest to oldest.
+type MovementFilter struct {
+	ProductID int64
+	Limit     int
+	After     string
+}
+
+type MovementPage struct {
+	Items      []*StockMovement `json:"items"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// StockMismatch is a product whose qty differs from the sum of its movements.
+type StockMismatch struct {
+	ProductID int64  `json:"product_id"`
+	Name      string `json:"name"`
+	Qty       int    `json:"qty"`
+	LedgerQty int    `json:"ledger_qty"`
+}
+
+// Return is a document which puts sold products back to stock.
+type Return struct {
+	ID        int64             `json:"id"`
+	SaleID    int64             `json:"sale_id"`
+	ManagerID int64             `json:"manager_id"`
+	Reason    string            `json:"reason"`
+	Total     Money             `json:"total"`
+	Created   time.Time         `json:"created"`
+	Positions []*ReturnPosition `json:"positions"`
+}
+
+// CalcTotals fills line totals of positions and total of the return.
+func (r *Return) CalcTotals() error {
+	r.Total = Money{}
+	for _, position := range r.Positions {
+		total, err := position.Price.Mul(int64(position.Qty))
+		if err != nil {
+			return err
+		}
+		position.Total = total
+		if r.Total, err = r.Total.Add(total); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type ReturnPosition struct {
+	ID             int64     `json:"id"`
+	ReturnID       int64     `json:"return_id"`
+	SalePositionID int64     `json:"sale_position_id"`
+	ProductID      int64     `json:"product_id"`
+	Price          Money     `json:"price"`
+	Qty            int       `json:"qty"`
+	Total          Money     `json:"total"`
+	Created        time.Time `json:"created"`
+}
+
+// SaleFilter describes a page of sales ordered from newest to oldest,
+// From is inclusive and To is exclusive, zero values mean "no filter".
+type SaleFilter struct {
+	Limit      int
+	After      string
+	From       time.Time
+	To         time.Time
+	CustomerID int64
+	ManagerID  int64
+}
+
+type SalePage struct {
+	Items      []*Sale `json:"items"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+const (
+	SortID      = "id"
+	SortName    = "name"
+	SortPrice   = "price"
+	SortCreated = "created"
+)
+
+// ProductSearch describes full-text search among active products.
+type ProductSearch struct {
+	Query string
+	Limit int
+}
+
+// ProductHit is a found product, Highlight is its name with matches wrapped into <b></b>.
+type ProductHit struct {
+	*Product
+	Rank      float64 `json:"rank"`
+	Highlight string  `json:"highlight"`
+}
+
+// ProductFilter describes a page of products, zero values mean "no filter".
+type ProductFilter struct {
+	Limit        int
+	After        string
+	Sort         string
+	Desc         bool
+	MinPrice     int
+	MaxPrice     int
+	InStock      bool
+	Name         string
+	WithInactive bool
+	// CategoryID matches products of the category and all its subcategories.
+	CategoryID int64
+}
+
+type ProductPage struct {
+	Items      []*Product `json:"items"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// CustomerFilter describes a page of active customers, Name matches name or phone.
+type CustomerFilter struct {
+	Limit int
+	After string
+	Sort  string
+	Desc  bool
+	Name  string
+}
+
+type CustomerPage struct {
+	Items      []*Customer `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
 type Customer struct {
-	ID      int64     `json:"id"`
-	Name    string    `json:"name"`
-	Phone   string    `json:"phone"`
-	Active  bool      `json:"active"`
-	Created time.Time `json:"created"`
+	ID       int64     `json:"id"`
+	Name     string    `json:"name"`
+	Phone    string    `json:"phone"`
+	Password string    `json:"password,omitempty"`
+	Active   bool      `json:"active"`
+	Created  time.Time `json:"created"`
 }
@@ -0,0 +1,98 @@
+package types
+
+import "testing"
+
+func newTestSale(t *testing.T, price, discount int64) *Sale {
+	t.Helper()
+	sale := &Sale{Positions: []*SalePosition{{
+		ProductID: 1,
+		Price:     NewMoney(price, "TJS"),
+		Qty:       1,
+		Discount:  NewMoney(discount, "TJS"),
+	}}}
+	if err := sale.CalcTotals(); err != nil {
+		t.Fatal(err)
+	}
+	return sale
+}
+
+func cash(amount int64) *Payment {
+	return &Payment{Tender: TenderCash, Amount: NewMoney(amount, "")}
+}
+
+func card(amount int64) *Payment {
+	return &Payment{Tender: TenderCard, Amount: NewMoney(amount, "")}
+}
+
+func TestSale_Pay(t *testing.T) {
+	tests := []struct {
+		name     string
+		payments []*Payment
+		status   string
+		due      int64
+		change   int64
+		err      error
+	}{
+		{"exact cash", []*Payment{cash(1000)}, SalePaid, 0, 0, nil},
+		{"cash with change", []*Payment{cash(1500)}, SalePaid, 0, 500, nil},
+		{"partial", []*Payment{card(400)}, SalePartiallyPaid, 600, 0, nil},
+		{"split", []*Payment{cash(1000), card(400)}, SalePaid, 0, 400, nil},
+		{"card over due", []*Payment{card(1200)}, SalePending, 1000, 0, ErrOverpayment},
+		{"currency mismatch", []*Payment{{Tender: TenderCash, Amount: NewMoney(1000, "USD")}}, SalePending, 1000, 0, ErrCurrencyMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sale := newTestSale(t, 1000, 0)
+			err := sale.Pay(tt.payments)
+			if err != tt.err {
+				t.Fatalf("Pay() error = %v, want %v", err, tt.err)
+			}
+			if sale.Status != tt.status || sale.Due.Amount != tt.due {
+				t.Errorf("Pay() status = %s, due = %d, want %s, %d", sale.Status, sale.Due.Amount, tt.status, tt.due)
+			}
+			change := int64(0)
+			for _, payment := range sale.Payments {
+				change += payment.Change.Amount
+			}
+			if change != tt.change {
+				t.Errorf("Pay() change = %d, want %d", change, tt.change)
+			}
+		})
+	}
+}
+
+func TestSale_Pay_nothingDue(t *testing.T) {
+	tests := []struct {
+		name     string
+		sale     *Sale
+		payments []*Payment
+		field    string
+	}{
+		{"free sale", newTestSale(t, 1000, 1000), []*Payment{cash(100)}, "payments"},
+		{"cash after due is covered", newTestSale(t, 1000, 0), []*Payment{card(1000), cash(100)}, "payments[1]"},
+		{"second cash", newTestSale(t, 1000, 0), []*Payment{cash(1000), cash(100)}, "payments[1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.sale.Pay(tt.payments)
+			validation, ok := err.(*ValidationError)
+			if !ok {
+				t.Fatalf("Pay() error = %v, want validation error", err)
+			}
+			if len(validation.Fields) != 1 || validation.Fields[0].Field != tt.field {
+				t.Errorf("Pay() fields = %v, want %q", validation.Fields, tt.field)
+			}
+			if len(tt.sale.Payments) != 0 {
+				t.Errorf("Pay() recorded %d payments", len(tt.sale.Payments))
+			}
+		})
+	}
+
+	sale := newTestSale(t, 1000, 0)
+	if err := sale.Pay([]*Payment{cash(1000)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sale.Pay([]*Payment{cash(100)}); err != ErrSalePaid {
+		t.Errorf("Pay() paid sale error = %v, want %v", err, ErrSalePaid)
+	}
+}
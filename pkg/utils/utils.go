@@ -3,7 +3,11 @@ package utils
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"github.com/bdaler/crud/pkg/types"
+	"github.com/jackc/pgconn"
+	"strings"
+	"unicode"
 )
 
 func GenerateTokenStr() (string, error) {
@@ -15,3 +19,22 @@ func GenerateTokenStr() (string, error) {
 
 	return hex.EncodeToString(buffer), nil
 }
+
+// IsUniqueViolation reports whether err is postgres unique_violation (23505).
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// IsForeignKeyViolation reports whether err is postgres foreign_key_violation (23503).
+func IsForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
+// SearchWords splits search query into lower case words of letters and digits.
+func SearchWords(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}